@@ -0,0 +1,144 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Codec定义了某一类型与键值之间的转换方法，可以通过RegisterCodec()注册。
+//
+//  ini.RegisterCodec(time.Time{}, &ini.Codec{
+//      Marshal: func(v interface{}) (string, error) {
+//          return v.(time.Time).Format("2006-01-02"), nil
+//      },
+//      Unmarshal: func(val string) (interface{}, error) {
+//          return time.Parse("2006-01-02", val)
+//      },
+//  })
+type Codec struct {
+	// 将该类型的值转换成键值，为nil时按默认的规则转换。
+	Marshal func(v interface{}) (string, error)
+
+	// 将键值转换成该类型的值，返回值必须可以赋值给该类型，为nil时按默认的规则转换。
+	Unmarshal func(val string) (interface{}, error)
+}
+
+var codecs = struct {
+	sync.RWMutex
+	items map[reflect.Type]*Codec
+}{items: map[reflect.Type]*Codec{
+	reflect.TypeOf(url.URL{}): urlCodec, // url.URL只实现了encoding.BinaryMarshaler
+}}
+
+var urlCodec = &Codec{
+	Marshal: func(v interface{}) (string, error) {
+		u := v.(url.URL)
+		return u.String(), nil
+	},
+	Unmarshal: func(val string) (interface{}, error) {
+		u, err := url.Parse(val)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+}
+
+// 注册v的类型对应的Codec，v为该类型的任意值，比如time.Time{}、[]string(nil)等，
+// 不能是指针，指向该类型的指针会自动使用相同的Codec。
+// 已经存在的会被覆盖，c为nil表示取消注册。url.URL默认已经注册了Codec。
+//
+// 注册之后，Decoder和Encoder在处理该类型的字段时，优先使用Codec进行转换，
+// 之后才是encoding.TextUnmarshaler和encoding.TextMarshaler接口，最后才是默认的规则。
+// 注册了Codec的结构体不再被当作section，切片也不再对应重复出现的键名。
+func RegisterCodec(v interface{}, c *Codec) {
+	t := reflect.TypeOf(v)
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	if c == nil {
+		delete(codecs.items, t)
+		return
+	}
+	codecs.items[t] = c
+}
+
+// 获取类型t对应的Codec，不存在则返回nil。
+func getCodec(t reflect.Type) *Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	return codecs.items[t]
+}
+
+// 类型t是否作为一个键值整体处理，即注册了Codec，
+// 或是实现了encoding.TextUnmarshaler或是encoding.TextMarshaler接口的类型，
+// 比如time.Time、url.URL和net.IP等。若t为指针，则判断其指向的类型。
+func isValue(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	p := reflect.PtrTo(t)
+	return getCodec(t) != nil || p.Implements(textUnmarshalerType) || p.Implements(textMarshalerType)
+}
+
+// 通过Codec或是encoding.TextUnmarshaler将val写入到v中，
+// v必须是可寻址的，ok表示是否有对应的转换方法。
+func unmarshalValue(v reflect.Value, val string) (ok bool, err error) {
+	if c := getCodec(v.Type()); c != nil && c.Unmarshal != nil {
+		ret, err := c.Unmarshal(val)
+		if err != nil {
+			return true, err
+		}
+
+		rv := reflect.ValueOf(ret)
+		if !rv.IsValid() || !rv.Type().AssignableTo(v.Type()) {
+			return true, fmt.Errorf("Codec返回的%T无法赋值给%v", ret, v.Type())
+		}
+		v.Set(rv)
+		return true, nil
+	}
+
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return true, u.UnmarshalText([]byte(val))
+		}
+	}
+
+	return false, nil
+}
+
+// 通过Codec或是encoding.TextMarshaler将v转换成字符串，ok表示是否有对应的转换方法。
+func marshalValue(v reflect.Value) (val string, ok bool, err error) {
+	if c := getCodec(v.Type()); c != nil && c.Marshal != nil {
+		val, err = c.Marshal(v.Interface())
+		return val, true, err
+	}
+
+	if !reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		return "", false, nil
+	}
+
+	if !v.CanAddr() { // 方法的接收者可能是指针
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+
+	data, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+	return string(data), true, err
+}
@@ -0,0 +1,164 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert"
+	"github.com/issue9/encoding/version"
+)
+
+type testLevel int
+
+func (l testLevel) MarshalText() ([]byte, error) {
+	switch l {
+	case 0:
+		return []byte("info"), nil
+	case 1:
+		return []byte("debug"), nil
+	}
+	return nil, errors.New("无效的值")
+}
+
+func (l *testLevel) UnmarshalText(data []byte) error {
+	switch string(data) {
+	case "info":
+		*l = 0
+	case "debug":
+		*l = 1
+	default:
+		return errors.New("无效的值")
+	}
+	return nil
+}
+
+type testCodecConfig struct {
+	IP      net.IP              `ini:"ip"`
+	URL     url.URL             `ini:"url"`
+	Proxy   *url.URL            `ini:"proxy"`
+	Level   testLevel           `ini:"level"`
+	Levels  []testLevel         `ini:"levels"`
+	Version *version.SemVersion `ini:"version"`
+	Started time.Time           `ini:"started"`
+	Tags    []string            `ini:"tags"`
+}
+
+func TestIsValue(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(isValue(reflect.TypeOf(time.Time{})))
+	a.True(isValue(reflect.TypeOf(&url.URL{})))
+	a.True(isValue(reflect.TypeOf(net.IP{})))
+	a.True(isValue(reflect.TypeOf(testLevel(0))))
+	a.False(isValue(reflect.TypeOf(testServer{})))
+	a.False(isValue(reflect.TypeOf([]string{})))
+
+	a.False(isSection(reflect.TypeOf(time.Time{})))
+	a.False(isSection(reflect.TypeOf(&url.URL{})))
+	a.False(isSlice(reflect.TypeOf(net.IP{})))
+	a.True(isSlice(reflect.TypeOf([]testLevel{})))
+
+	RegisterCodec(testServer{}, &Codec{})
+	a.True(isValue(reflect.TypeOf(&testServer{}))).False(isSection(reflect.TypeOf(testServer{})))
+	RegisterCodec(testServer{}, nil)
+	a.False(isValue(reflect.TypeOf(testServer{}))).True(isSection(reflect.TypeOf(testServer{})))
+}
+
+func TestTextMarshaler(t *testing.T) {
+	a := assert.New(t)
+
+	data := `ip=192.168.1.1
+url=https://example.com/path?q=1
+proxy=http://localhost:8080
+level=debug
+levels=debug
+levels=info
+version=1.2.3-alpha
+started=2020-01-02T03:04:05Z
+`
+	conf := &testCodecConfig{}
+	a.NotError(Unmarshal([]byte(data), conf))
+	a.Equal(conf.IP.String(), "192.168.1.1")
+	a.Equal(conf.URL.Host, "example.com").Equal(conf.URL.RawQuery, "q=1")
+	a.Equal(conf.Proxy.Host, "localhost:8080")
+	a.Equal(conf.Level, 1).Equal(conf.Levels, []testLevel{1, 0})
+	a.Equal(conf.Version, &version.SemVersion{Major: 1, Minor: 2, Patch: 3, PreRelease: "alpha"})
+	a.Equal(conf.Started, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	out, err := Marshal(conf)
+	a.NotError(err)
+	a.Equal(string(out), data)
+
+	// 值类型的结构体，MarshalText()的接收者为指针
+	out, err = Marshal(struct {
+		Version version.SemVersion `ini:"version"`
+	}{Version: version.SemVersion{Major: 1}})
+	a.NotError(err).Equal(string(out), "version=1.0.0\n")
+
+	// 转换失败
+	err = Unmarshal([]byte("level=trace"), conf)
+	a.Error(err).True(strings.Contains(err.Error(), "level"))
+	_, err = Marshal(&testCodecConfig{Level: 5})
+	a.Error(err)
+}
+
+func TestRegisterCodec(t *testing.T) {
+	a := assert.New(t)
+
+	RegisterCodec(time.Time{}, &Codec{
+		Marshal: func(v interface{}) (string, error) {
+			return v.(time.Time).Format("2006-01-02"), nil
+		},
+		Unmarshal: func(val string) (interface{}, error) {
+			return time.Parse("2006-01-02", val)
+		},
+	})
+	defer RegisterCodec(time.Time{}, nil)
+
+	RegisterCodec([]string(nil), &Codec{
+		Marshal: func(v interface{}) (string, error) {
+			return strings.Join(v.([]string), ","), nil
+		},
+		Unmarshal: func(val string) (interface{}, error) {
+			return strings.Split(val, ","), nil
+		},
+	})
+	defer RegisterCodec([]string(nil), nil)
+
+	conf := &testCodecConfig{}
+	a.NotError(Unmarshal([]byte("started=2020-01-02\ntags=a,b,c"), conf))
+	a.Equal(conf.Started, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	a.Equal(conf.Tags, []string{"a", "b", "c"})
+
+	out, err := Marshal(&struct {
+		Started time.Time  `ini:"started"`
+		Tags    []string   `ini:"tags"`
+		Ptr     *time.Time `ini:"ptr"`
+	}{Started: conf.Started, Tags: conf.Tags, Ptr: &conf.Started})
+	a.NotError(err)
+	a.Equal(string(out), "started=2020-01-02\ntags=a,b,c\nptr=2020-01-02\n")
+
+	// 返回值类型不匹配
+	RegisterCodec(testLevel(0), &Codec{
+		Unmarshal: func(val string) (interface{}, error) {
+			return val, nil
+		},
+	})
+	defer RegisterCodec(testLevel(0), nil)
+	a.Error(Unmarshal([]byte("level=debug"), conf))
+
+	// Marshal为nil时，使用MarshalText()
+	out, err = Marshal(&struct {
+		Level testLevel `ini:"level"`
+	}{Level: 1})
+	a.NotError(err).Equal(string(out), "level=debug\n")
+}
@@ -0,0 +1,304 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通过Config获取值时，若键名不存在，则返回的错误信息可以通过errors.Is()与此值比较。
+var ErrKeyNotFound = errors.New("键名不存在")
+
+// Config对UnmarshalMap()的结果提供类型化的访问方法。
+//
+//  cfg, err := ini.UnmarshalConfig(data)
+//  port, err := cfg.Section("server").Int("port")
+//  timeout := cfg.Section("server").MustDuration("timeout", time.Second)
+type Config struct {
+	data    map[string]map[string]string
+	sources map[string]map[string]string // 各个值的来源，由Loader设置
+}
+
+// ConfigSection表示Config中的一个section，提供各类型的访问方法。
+//
+// 所有的XX()方法在键名不存在或是无法转换时，返回包含section和键名的错误信息；
+// MustXX()方法则在这些情况下返回指定的默认值。
+type ConfigSection struct {
+	name    string
+	data    map[string]string
+	sources map[string]string
+}
+
+// 声明一个Config实例，data的格式与UnmarshalMap()的返回值相同，
+// 索引值为空的元素表示非section下的键值对。
+func NewConfig(data map[string]map[string]string) *Config {
+	if data == nil {
+		data = map[string]map[string]string{}
+	}
+
+	return &Config{data: data}
+}
+
+// 将ini格式的数据转换成Config，规则与UnmarshalMap()相同。
+func UnmarshalConfig(data []byte) (*Config, error) {
+	m, err := UnmarshalMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConfig(m), nil
+}
+
+// 获取名称为name的section，name为空表示非section下的键值对。
+// section不存在时，返回一个空的ConfigSection，而不是nil。
+func (c *Config) Section(name string) *ConfigSection {
+	return &ConfigSection{name: name, data: c.data[name], sources: c.sources[name]}
+}
+
+// 获取所有的section名称，按字母顺序排列，不包含非section下的键值对。
+func (c *Config) Sections() []string {
+	names := make([]string, 0, len(c.data))
+	for name := range c.data {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// 当前section的名称
+func (s *ConfigSection) Name() string {
+	return s.name
+}
+
+// 是否存在名称为key的键
+func (s *ConfigSection) Has(key string) bool {
+	_, found := s.data[key]
+	return found
+}
+
+// 获取名称为key的值的来源，比如文件名或是env:APP_SERVER_PORT等，
+// 仅由Loader生成的Config才有此信息，否则返回空字符串。
+func (s *ConfigSection) Source(key string) string {
+	return s.sources[key]
+}
+
+// 获取所有的键名，按字母顺序排列。
+func (s *ConfigSection) Keys() []string {
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// 获取名称为key的原始值，fn为调用的方法名称，用于生成错误信息。
+func (s *ConfigSection) get(fn, key string) (string, error) {
+	val, found := s.data[key]
+	if !found {
+		return "", fmt.Errorf("%v:[%v]中的%w：%v", fn, s.name, ErrKeyNotFound, key)
+	}
+
+	return val, nil
+}
+
+// 生成转换失败的错误信息。
+func (s *ConfigSection) convertError(fn, key, val string, err error) error {
+	return fmt.Errorf("%v:无法转换[%v]中%v的值%v：%w", fn, s.name, key, val, err)
+}
+
+// 获取字符串类型的值
+func (s *ConfigSection) String(key string) (string, error) {
+	return s.get("String", key)
+}
+
+// 功能同String()，但在出错时返回def
+func (s *ConfigSection) MustString(key, def string) string {
+	if val, err := s.String(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取整数类型的值，支持0x、0o和0b等进制前缀。
+func (s *ConfigSection) Int(key string) (int64, error) {
+	val, err := s.get("Int", key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(val, 0, 64)
+	if err != nil {
+		return 0, s.convertError("Int", key, val, err)
+	}
+	return n, nil
+}
+
+// 功能同Int()，但在出错时返回def
+func (s *ConfigSection) MustInt(key string, def int64) int64 {
+	if val, err := s.Int(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取无符号整数类型的值，支持0x、0o和0b等进制前缀。
+func (s *ConfigSection) Uint(key string) (uint64, error) {
+	val, err := s.get("Uint", key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(val, 0, 64)
+	if err != nil {
+		return 0, s.convertError("Uint", key, val, err)
+	}
+	return n, nil
+}
+
+// 功能同Uint()，但在出错时返回def
+func (s *ConfigSection) MustUint(key string, def uint64) uint64 {
+	if val, err := s.Uint(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取浮点类型的值
+func (s *ConfigSection) Float(key string) (float64, error) {
+	val, err := s.get("Float", key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, s.convertError("Float", key, val, err)
+	}
+	return n, nil
+}
+
+// 功能同Float()，但在出错时返回def
+func (s *ConfigSection) MustFloat(key string, def float64) float64 {
+	if val, err := s.Float(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取布尔类型的值，具体规则可参考parseBool()。
+func (s *ConfigSection) Bool(key string) (bool, error) {
+	val, err := s.get("Bool", key)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := parseBool(val)
+	if err != nil {
+		return false, s.convertError("Bool", key, val, err)
+	}
+	return b, nil
+}
+
+// 功能同Bool()，但在出错时返回def
+func (s *ConfigSection) MustBool(key string, def bool) bool {
+	if val, err := s.Bool(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取time.Duration类型的值，格式与time.ParseDuration()相同。
+func (s *ConfigSection) Duration(key string) (time.Duration, error) {
+	val, err := s.get("Duration", key)
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, s.convertError("Duration", key, val, err)
+	}
+	return d, nil
+}
+
+// 功能同Duration()，但在出错时返回def
+func (s *ConfigSection) MustDuration(key string, def time.Duration) time.Duration {
+	if val, err := s.Duration(key); err == nil {
+		return val
+	}
+	return def
+}
+
+// 按layout的格式获取time.Time类型的值，layout的格式与time.Parse()相同。
+func (s *ConfigSection) Time(key, layout string) (time.Time, error) {
+	val, err := s.get("Time", key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(layout, val)
+	if err != nil {
+		return time.Time{}, s.convertError("Time", key, val, err)
+	}
+	return t, nil
+}
+
+// 功能同Time()，但在出错时返回def
+func (s *ConfigSection) MustTime(key, layout string, def time.Time) time.Time {
+	if val, err := s.Time(key, layout); err == nil {
+		return val
+	}
+	return def
+}
+
+// 获取以sep分隔的列表，每一项都会去掉首尾的空白字符，空的项会被忽略。
+func (s *ConfigSection) List(key, sep string) ([]string, error) {
+	val, err := s.get("List", key)
+	if err != nil {
+		return nil, err
+	}
+
+	items := strings.Split(val, sep)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// 功能同List()，但在出错时返回def
+func (s *ConfigSection) MustList(key, sep string, def []string) []string {
+	if val, err := s.List(key, sep); err == nil {
+		return val
+	}
+	return def
+}
+
+// 将字符串转换成布尔值，不区分大小写，Decoder和ConfigSection都使用此规则：
+//  true、yes、on、1   // true
+//  false、no、off、0  // false
+// 其它的值则按strconv.ParseBool()的规则转换。
+func parseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+
+	return strconv.ParseBool(val)
+}
@@ -0,0 +1,133 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert"
+)
+
+const configTestData = `
+debug = on
+[server]
+port = 8080
+max = 0x10
+ratio = 0.75
+timeout = 5s
+started = 2020-01-02
+hosts = a.example.com, b.example.com,,
+enabled = Yes
+invalid = abc
+`
+
+func TestUnmarshalConfig(t *testing.T) {
+	a := assert.New(t)
+
+	cfg, err := UnmarshalConfig([]byte(configTestData))
+	a.NotError(err).NotNil(cfg)
+	a.Equal(cfg.Sections(), []string{"server"})
+
+	global := cfg.Section("")
+	a.Equal(global.Name(), "").True(global.Has("debug"))
+	b, err := global.Bool("debug")
+	a.NotError(err).True(b)
+
+	none := cfg.Section("none")
+	a.NotNil(none).False(none.Has("port")).Empty(none.Keys())
+
+	cfg, err = UnmarshalConfig([]byte("[s"))
+	a.Error(err).Nil(cfg)
+
+	a.NotNil(NewConfig(nil).Section("s"))
+}
+
+func TestConfigSection(t *testing.T) {
+	a := assert.New(t)
+
+	cfg, err := UnmarshalConfig([]byte(configTestData))
+	a.NotError(err).NotNil(cfg)
+	s := cfg.Section("server")
+	a.Equal(s.Keys(), []string{"enabled", "hosts", "invalid", "max", "port", "ratio", "started", "timeout"})
+
+	str, err := s.String("port")
+	a.NotError(err).Equal(str, "8080")
+	a.Equal(s.MustString("none", "def"), "def")
+
+	i, err := s.Int("port")
+	a.NotError(err).Equal(i, 8080)
+	i, err = s.Int("max")
+	a.NotError(err).Equal(i, 16)
+	a.Equal(s.MustInt("invalid", 5), 5)
+
+	u, err := s.Uint("port")
+	a.NotError(err).Equal(u, 8080)
+	a.Equal(s.MustUint("none", 5), 5)
+
+	f, err := s.Float("ratio")
+	a.NotError(err).Equal(f, 0.75)
+	a.Equal(s.MustFloat("invalid", 1.5), 1.5)
+
+	b, err := s.Bool("enabled")
+	a.NotError(err).True(b)
+	a.False(s.MustBool("invalid", false))
+
+	d, err := s.Duration("timeout")
+	a.NotError(err).Equal(d, 5*time.Second)
+	a.Equal(s.MustDuration("invalid", time.Minute), time.Minute)
+
+	tm, err := s.Time("started", "2006-01-02")
+	a.NotError(err).Equal(tm, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	def := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	a.Equal(s.MustTime("started", time.RFC3339, def), def)
+
+	list, err := s.List("hosts", ",")
+	a.NotError(err).Equal(list, []string{"a.example.com", "b.example.com"})
+	a.Equal(s.MustList("none", ",", []string{"x"}), []string{"x"})
+
+	// 错误信息
+	_, err = s.Int("none")
+	a.Error(err).True(errors.Is(err, ErrKeyNotFound))
+	a.True(strings.Contains(err.Error(), "[server]")).True(strings.Contains(err.Error(), "none"))
+
+	_, err = s.Int("invalid")
+	a.Error(err).False(errors.Is(err, ErrKeyNotFound))
+	a.True(strings.Contains(err.Error(), "[server]")).True(strings.Contains(err.Error(), "invalid"))
+
+	_, err = s.Uint("invalid")
+	a.Error(err)
+	_, err = s.Float("invalid")
+	a.Error(err)
+	_, err = s.Bool("invalid")
+	a.Error(err)
+	_, err = s.Duration("invalid")
+	a.Error(err)
+	_, err = s.Time("invalid", time.RFC3339)
+	a.Error(err)
+	_, err = s.List("none", ",")
+	a.Error(err)
+}
+
+func TestParseBool(t *testing.T) {
+	a := assert.New(t)
+
+	for _, val := range []string{"true", "TRUE", "yes", "On", "1", "t"} {
+		b, err := parseBool(val)
+		a.NotError(err).True(b, val)
+	}
+
+	for _, val := range []string{"false", "No", "OFF", "0", "f"} {
+		b, err := parseBool(val)
+		a.NotError(err).False(b, val)
+	}
+
+	_, err := parseBool("")
+	a.Error(err)
+	_, err = parseBool("y")
+	a.Error(err)
+}
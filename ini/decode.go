@@ -0,0 +1,707 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Decoder从io.Reader中读取ini数据并解码到结构体中。
+type Decoder struct {
+	r                       *Reader
+	strict                  bool
+	disallowUnknownKeys     bool
+	disallowUnknownSections bool
+	subsection              bool
+	repeated                bool
+	duplicate               Duplicate
+	interpolation           *Interpolation
+	inherit                 bool
+	tolerant                bool
+	errs                    []error // 容错模式下收集到的错误
+
+	invalid SyntaxErrors              // 未知的键名和section，以及缺少的必需项
+	visited map[visitedField]struct{} // 已经有对应section的字段
+}
+
+// 表示结构体类型typ中索引值为index的字段
+type visitedField struct {
+	typ   reflect.Type
+	index int
+}
+
+// 声明一个新的Decoder实例，数据从r中读取。
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderReader(NewReader(r))
+}
+
+// 声明一个新的Decoder实例，数据从Reader中读取。
+//
+// 可用于解码由NewReaderFS()等方法创建的，或是修改了默认选项的Reader。
+func NewDecoderReader(r *Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// 启用严格模式。
+//
+// 在严格模式下，键名和section名称必须与字段名称完全相同，
+// 不再进行大小写无关的匹配。
+func (dec *Decoder) Strict() {
+	dec.strict = true
+}
+
+// 当键名找不到对应的字段时，返回错误信息，而不是忽略。
+//
+// 仅对能找到对应字段的section下的键值对有效，
+// 无法找到对应字段的section，其下的所有内容依然会被忽略，
+// 可以通过DisallowUnknownSections()检测这些section。
+//
+// 所有未知的键名会在解码完成之后，以SyntaxErrors的形式一起返回，
+// 其中的错误可以通过errors.Is()与ErrUnknownKey比较。
+func (dec *Decoder) DisallowUnknownKeys() {
+	dec.disallowUnknownKeys = true
+}
+
+// 当section找不到对应的字段时，返回错误信息，而不是忽略。
+//
+// 所有未知的section会在解码完成之后，以SyntaxErrors的形式一起返回，
+// 其中的错误可以通过errors.Is()与ErrUnknownSection比较。
+func (dec *Decoder) DisallowUnknownSections() {
+	dec.disallowUnknownSections = true
+}
+
+// 启用容错模式。
+//
+// 在容错模式下，语法错误、无法转换的键值、重复或是未知的键名等错误，
+// 都不会中断解码过程，而是在解码完成之后，通过errors.Join()一起返回，
+// 其中的语法错误以SyntaxErrors的形式返回。出错的键值对不会修改对应的字段。
+func (dec *Decoder) Tolerant() {
+	dec.tolerant = true
+	dec.r.Tolerant()
+}
+
+// 在容错模式下，保存err并返回nil，否则原样返回err。
+func (dec *Decoder) fail(err error) error {
+	if dec.tolerant {
+		dec.errs = append(dec.errs, err)
+		return nil
+	}
+	return err
+}
+
+// 记录一个未知的键名或是section，在解码完成之后一起返回。
+func (dec *Decoder) report(line int, err error, format string, v ...interface{}) {
+	dec.invalid = append(dec.invalid, &SyntaxError{
+		Line: line,
+		Msg:  "Decode:" + fmt.Sprintf(format, v...) + "：" + err.Error(),
+		Err:  err,
+	})
+}
+
+// RequiredError表示Decoder.Decode()时缺少的必需项。
+//
+// 与未知的键名和section一起包含在SyntaxErrors中，作为SyntaxError.Err返回，
+// 此时SyntaxError.Line总是为0，SyntaxError.Error()也不再包含行号。
+// 可以通过errors.Is(err, ErrRequired)或是errors.As()判断。
+type RequiredError struct {
+	Section string // 所在的section名称，为空表示全局的键名
+	Key     string // 缺少的键名，为空表示缺少的是Section本身
+}
+
+func (err *RequiredError) Error() string {
+	switch {
+	case len(err.Key) == 0:
+		return fmt.Sprintf("Decode:缺少必需的section[%v]", err.Section)
+	case len(err.Section) == 0:
+		return fmt.Sprintf("Decode:缺少必需的键名%v", err.Key)
+	default:
+		return fmt.Sprintf("Decode:[%v]中缺少必需的键名%v", err.Section, err.Key)
+	}
+}
+
+func (err *RequiredError) Unwrap() error {
+	return ErrRequired
+}
+
+// 记录一个缺少的必需项，key为空表示缺少的是section本身。
+func (dec *Decoder) required(section, key string) {
+	err := &RequiredError{Section: section, Key: key}
+	dec.invalid = append(dec.invalid, &SyntaxError{Msg: err.Error(), Err: err})
+}
+
+// 设置重复键名的处理方式，默认为DuplicateLast。
+//
+// 同名的section会先合并再解码，键名的重复判断同样跨越这些section，
+// 若d为DuplicateError，则同名的section也会返回错误信息。
+// 切片类型的字段总是按顺序保存所有的键值，不受d的影响；
+// 对于其它类型的字段，DuplicateList与DuplicateError相同。
+func (dec *Decoder) SetDuplicate(d Duplicate) {
+	dec.duplicate = d
+}
+
+// 启用子section，将section名称解析成层级路径，并对应到嵌套的结构体字段：
+//  type Config struct {
+//      Server struct {
+//          HTTP struct {
+//              Port int `ini:"port"`
+//          } `ini:"http"`
+//      } `ini:"server"`
+//      Remotes map[string]Remote `ini:"remote"`
+//  }
+// 其中[server.http]对应于Server.HTTP，[remote "origin"]对应于Remotes["origin"]，
+// map字段的键值类型可以是结构体或是指向结构体的指针，map为nil时会自动创建。
+// 若存在与section完整名称相同的字段，则优先使用该字段。
+//
+// section名称的解析规则可参考Reader.AllowSubsection()。
+func (dec *Decoder) AllowSubsection() {
+	dec.subsection = true
+	dec.r.AllowSubsection()
+}
+
+// 启用重复的section，同名的section不再合并，而是依次解码到切片类型的字段中：
+//  type Config struct {
+//      Servers []Server `ini:"server"`
+//  }
+// 每个[server]都对应Servers中的一个元素，元素的类型可以是结构体或是指向结构体的指针，
+// 元素中不能再包含子section。仅对能找到对应切片字段的section有效，
+// 其它的section依然会被合并，切片字段也依然只对应合并之后的一个元素。
+func (dec *Decoder) AllowRepeatedSections() {
+	dec.repeated = true
+}
+
+// 在解码之前，对所有的键值进行变量替换，具体规则可参考Interpolation。
+// opt为nil时，表示使用默认的配置。
+func (dec *Decoder) Interpolate(opt *Interpolation) {
+	if opt == nil {
+		opt = &Interpolation{}
+	}
+	dec.interpolation = opt
+}
+
+// 启用section继承，[child : parent]形式的section将继承parent中的键值对，
+// 之后再以child作为section名称进行解码，具体规则可参考Inherit()。
+// 父section不存在或是存在循环继承时，Decode()将返回错误信息。
+//
+// 与AllowRepeatedSections()同时使用时，重复的section不能声明父section，
+// 也不能作为其它section的父section，否则Decode()将返回错误信息。
+func (dec *Decoder) AllowInheritance() {
+	dec.inherit = true
+}
+
+// 从输入流中读取所有的内容，并解码到v中，v只能是指向结构体的指针。
+//
+// 结构体中的非结构体字段对应于全局（不属于任何section）的键值对，
+// 结构体字段（或是指向结构体的指针）则对应于同名的section，
+// 其下的字段对应于该section下的键值对：
+//  type Config struct {
+//      Name   string `ini:"name"`
+//      Server struct {
+//          Port    int           `ini:"port"`
+//          Timeout time.Duration `ini:"timeout"`
+//      } `ini:"server"`
+//  }
+// 对应以下内容：
+//  name=app
+//  [server]
+//  port=8080
+//  timeout=5s
+//
+// 字段名称的查找，优先匹配名称完全相同的字段，若不存在且未启用严格模式，
+// 则忽略大小写再次查找。无法找到对应字段的键值对和section将被忽略。
+//
+// 字段类型只能是字符串、布尔值、整数、浮点数和time.Duration，
+// 实现了encoding.TextUnmarshaler接口或是通过RegisterCodec()注册了Codec的类型，
+// 以及指向这些类型的指针，或是由这些类型组成的切片。
+// 布尔值除了true和false之外，还可以是yes、no、on、off、1和0，不区分大小写。
+// 切片类型的字段对应于重复出现的键名，或是以`[]`结尾的键名：
+//  path[] = /usr
+//  path[] = /opt
+//
+// 可以在struct tag中通过required选项声明必需的键名或是section：
+//  Port   int    `ini:"port;required"`
+//  Server Server `ini:"server;required"`
+// 所有缺少的必需项，与DisallowUnknownKeys()和DisallowUnknownSections()
+// 检测到的问题一起，在解码完成之后以SyntaxErrors的形式返回，
+// 缺少的必需项以RequiredError表示，没有行号，排在最前面。
+// 只检测实际存在的section中的必需键名，
+// section本身是否必需，由其在上一级中对应字段的required选项决定。
+//
+// 通过default选项可以为字段指定默认值，在字段为零值且没有对应的键名时使用，
+// 切片类型的字段，默认值中以逗号分隔的每一项都作为一个元素：
+//  Port  int      `ini:"port;default(8080)"`
+//  Paths []string `ini:"paths;default(/usr,/opt)"`
+// 空的默认值，比如default()，与没有默认值相同。
+// 指针类型的section字段，若为nil且没有对应的section，则保持为nil，
+// 其中的默认值也不会被使用；需要默认值时，可以使用非指针类型的字段。
+func (dec *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("Decode:参数v只能是非空指针")
+	}
+
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return errors.New("Decode:参数v只能是指向结构体的指针")
+	}
+	if err := checkFields(rv.Type()); err != nil {
+		return fmt.Errorf("Decode:%v", err)
+	}
+
+	dec.errs = nil
+	dec.invalid = nil
+	dec.visited = make(map[visitedField]struct{})
+	f, err := LoadFile(dec.r)
+	if err != nil {
+		if f == nil {
+			return err
+		}
+		dec.errs = append(dec.errs, err) // 容错模式下的SyntaxErrors
+	}
+
+	if err = dec.setDefaults(rv, ""); err != nil {
+		return err
+	}
+
+	var parents map[string][]string
+	if dec.inherit {
+		parents = splitInheritSections(f.Sections)
+	}
+
+	sections, duplicates := dec.mergeSections(rv.Type(), f.Sections)
+
+	if dec.inherit {
+		if err = inheritSections(sections, parents); err != nil {
+			if err = dec.fail(fmt.Errorf("Decode:%v", err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 在继承之后进行变量替换，继承的键值以子section的内容进行替换。
+	if dec.interpolation != nil {
+		if err = interpolateFile(&File{Global: f.Global, Sections: sections}, dec.interpolation); err != nil {
+			return err
+		}
+	}
+
+	if err = dec.decodeSection(rv, f.Global); err != nil {
+		return err
+	}
+
+	for _, err := range duplicates {
+		if err = dec.fail(err); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range sections {
+		path, err := dec.sectionPath(rv.Type(), s.Name)
+		if err != nil {
+			if err = dec.fail(fmt.Errorf("Decode:第%d行的section名称%v无效：%v", s.line, s.Name, err)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = dec.decodePath(rv, path, s); err != nil {
+			return err
+		}
+	}
+
+	dec.checkRequiredSections(rv.Type())
+	if len(dec.invalid) > 0 {
+		sortSyntaxErrors(dec.invalid)
+		if !dec.tolerant {
+			return dec.invalid
+		}
+		dec.errs = append(dec.errs, dec.invalid)
+	}
+
+	if len(dec.errs) > 0 {
+		return errors.Join(dec.errs...)
+	}
+	return nil
+}
+
+// 获取section名称在结构体类型t中对应的层级路径。
+func (dec *Decoder) sectionPath(t reflect.Type, name string) ([]string, error) {
+	if dec.subsection && findField(getFields(t), name, true, !dec.strict) == nil {
+		return splitSection(name)
+	}
+
+	return []string{name}, nil
+}
+
+// 名称为name的section是否对应于结构体类型t中的切片字段，且启用了重复的section。
+func (dec *Decoder) isRepeated(t reflect.Type, name string) bool {
+	if !dec.repeated {
+		return false
+	}
+
+	path, err := dec.sectionPath(t, name)
+	if err != nil {
+		return false
+	}
+
+	for len(path) > 0 {
+		f := findField(getFields(t), path[0], true, !dec.strict)
+		switch {
+		case f == nil:
+			return false
+		case isSectionSlice(f.typ):
+			return len(path) == 1
+		case isSectionMap(f.typ):
+			if len(path) < 2 {
+				return false
+			}
+			t, path = f.typ.Elem(), path[2:]
+		default:
+			t, path = f.typ, path[1:]
+		}
+
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	return false
+}
+
+// 合并同名的section，合并之后的section位于第一次出现的位置，
+// 对应于切片字段的重复section不会被合并，具体可参考AllowRepeatedSections()。
+// t为解码的目标结构体类型。
+//
+// 若设置了DuplicateError，同时返回重复的section的错误信息，由调用方决定何时报告，
+// 以保证全局键值对中的错误优先于section中的错误。
+func (dec *Decoder) mergeSections(t reflect.Type, sections []*FileSection) ([]*FileSection, []error) {
+	ret := make([]*FileSection, 0, len(sections))
+	var errs []error
+	merged := make(map[string]*FileSection, len(sections))
+
+	for _, s := range sections {
+		if dec.isRepeated(t, s.Name) {
+			ret = append(ret, &FileSection{
+				Name: s.Name,
+				Keys: append(make([]*FileKey, 0, len(s.Keys)), s.Keys...),
+				line: s.line,
+			})
+			continue
+		}
+
+		if m, found := merged[s.Name]; found {
+			if dec.duplicate == DuplicateError {
+				errs = append(errs, fmt.Errorf("Decode:第%d行的section[%v]重复，之前已在第%d行声明", s.line, s.Name, m.line))
+			}
+			m.Keys = append(m.Keys, s.Keys...)
+			continue
+		}
+
+		m := &FileSection{
+			Name: s.Name,
+			Keys: append(make([]*FileKey, 0, len(s.Keys)), s.Keys...),
+			line: s.line,
+		}
+		merged[s.Name] = m
+		ret = append(ret, m)
+	}
+
+	return ret, errs
+}
+
+// 将section s解码到结构体v中路径为path的字段，
+// 路径中任意一级找不到对应的字段，都将忽略该section。
+func (dec *Decoder) decodePath(v reflect.Value, path []string, s *FileSection) error {
+	if len(path) == 0 {
+		return dec.decodeSection(v, s)
+	}
+
+	f := findField(getFields(v.Type()), path[0], true, !dec.strict)
+	if f == nil {
+		dec.unknownSection(s)
+		return nil
+	}
+	vf := visitedField{typ: v.Type(), index: f.index}
+	_, visited := dec.visited[vf]
+	dec.visited[vf] = struct{}{}
+
+	fv := v.Field(f.index)
+	if isSectionSlice(f.typ) {
+		if len(path) > 1 { // 切片中的元素不能再包含子section
+			dec.unknownSection(s)
+			return nil
+		}
+
+		if !visited { // 第一次出现时，清除原有的内容。
+			fv.Set(reflect.MakeSlice(f.typ, 0, 1))
+		}
+
+		elem := reflect.New(f.typ.Elem()).Elem()
+		if err := dec.decodeSection(indirect(elem), s); err != nil {
+			return err
+		}
+		fv.Set(reflect.Append(fv, elem))
+		return nil
+	}
+	if !isSectionMap(f.typ) {
+		return dec.decodePath(indirect(fv), path[1:], s)
+	}
+
+	if len(path) < 2 { // map字段需要子section名称作为键名
+		dec.unknownSection(s)
+		return nil
+	}
+
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(f.typ))
+	}
+
+	// map中的元素不可寻址，需要复制一份修改之后再写回。
+	key := reflect.ValueOf(path[1]).Convert(f.typ.Key())
+	elem := reflect.New(f.typ.Elem()).Elem()
+	if old := fv.MapIndex(key); old.IsValid() {
+		elem.Set(old)
+	}
+
+	if err := dec.decodePath(indirect(elem), path[2:], s); err != nil {
+		return err
+	}
+	fv.SetMapIndex(key, elem)
+	return nil
+}
+
+func (dec *Decoder) unknownSection(s *FileSection) {
+	if dec.disallowUnknownSections {
+		dec.report(s.line, ErrUnknownSection, "[%v]没有对应的字段", s.Name)
+	}
+}
+
+// 检测结构体类型t中声明为required的section字段是否都有对应的section，
+// 仅检测有对应section的字段之下的内容。
+func (dec *Decoder) checkRequiredSections(t reflect.Type) {
+	for _, f := range getFields(t) {
+		if !isSection(f.typ) {
+			continue
+		}
+
+		if _, found := dec.visited[visitedField{typ: t, index: f.index}]; !found {
+			if f.required {
+				dec.required(f.name, "")
+			}
+			continue
+		}
+
+		typ := f.typ
+		if isSectionMap(typ) || isSectionSlice(typ) {
+			typ = typ.Elem()
+		}
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		dec.checkRequiredSections(typ)
+	}
+}
+
+// 将section中的键值对解码到结构体v中。
+//
+// 以`[]`结尾的键名，将去掉`[]`之后再查找对应的字段。
+func (dec *Decoder) decodeSection(v reflect.Value, s *FileSection) error {
+	fields := getFields(v.Type())
+	decoded := make(map[*field]*FileKey, len(fields)) // 已经解码的字段及其对应的键值对
+
+	for _, k := range s.Keys {
+		name, _ := arrayKey(k.Name)
+		f := findField(fields, name, false, !dec.strict)
+		if f == nil {
+			if dec.disallowUnknownKeys {
+				dec.report(k.line, ErrUnknownKey, "键名%v在[%v]中没有对应的字段", k.Name, s.Name)
+			}
+			continue
+		}
+
+		fv := v.Field(f.index)
+		prev, found := decoded[f]
+		if !found {
+			decoded[f] = k
+		}
+
+		if isSlice(f.typ) {
+			sv := indirect(fv)
+			if !found { // 第一次出现时，清除原有的内容。
+				sv.Set(reflect.MakeSlice(sv.Type(), 0, 1))
+			}
+
+			elem := reflect.New(sv.Type().Elem()).Elem()
+			if err := setValue(elem, k.Value); err != nil {
+				if err = dec.fail(fmt.Errorf("Decode:无法将[%v]中的%v转换成%v类型：%v", s.Name, k.Name, f.typ, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			sv.Set(reflect.Append(sv, elem))
+			continue
+		}
+
+		if found {
+			switch dec.duplicate {
+			case DuplicateFirst:
+				continue
+			case DuplicateError, DuplicateList:
+				if err := dec.fail(fmt.Errorf("Decode:第%d行的键名%v在[%v]中重复，之前已在第%d行声明", k.line, k.Name, s.Name, prev.line)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := setValue(fv, k.Value); err != nil {
+			if err = dec.fail(fmt.Errorf("Decode:无法将[%v]中的%v转换成%v类型：%v", s.Name, k.Name, f.typ, err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if _, found := decoded[f]; found || isSection(f.typ) {
+			continue
+		}
+
+		if err := dec.setDefault(v.Field(f.index), f, s.Name); err != nil {
+			return err
+		}
+
+		if f.required {
+			dec.required(s.Name, f.name)
+		}
+	}
+
+	return nil
+}
+
+// 将结构体v及其结构体类型的section字段中，所有声明了默认值的字段设置为默认值，
+// 空指针和map类型的section字段不作处理，这些字段会在解码对应的section时设置默认值。
+func (dec *Decoder) setDefaults(v reflect.Value, section string) error {
+	for _, f := range getFields(v.Type()) {
+		fv := v.Field(f.index)
+		if !isSection(f.typ) {
+			if err := dec.setDefault(fv, f, section); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if sv := elemValue(fv); sv.IsValid() && sv.Kind() == reflect.Struct {
+			if err := dec.setDefaults(sv, f.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 若字段f声明了默认值，且fv为零值，则将默认值写入到fv中。
+func (dec *Decoder) setDefault(fv reflect.Value, f *field, section string) error {
+	if !f.hasDefault || !fv.IsZero() {
+		return nil
+	}
+
+	var err error
+	if isSlice(f.typ) {
+		sv := indirect(fv)
+		sv.Set(reflect.MakeSlice(sv.Type(), 0, len(f.defaults)))
+		for _, d := range f.defaults {
+			elem := reflect.New(sv.Type().Elem()).Elem()
+			if err = setValue(elem, d); err != nil {
+				break
+			}
+			sv.Set(reflect.Append(sv, elem))
+		}
+	} else {
+		err = setValue(fv, strings.Join(f.defaults, ","))
+	}
+
+	if err != nil {
+		return dec.fail(fmt.Errorf("Decode:无法将[%v]中%v的默认值转换成%v类型：%v", section, f.name, f.typ, err))
+	}
+	return nil
+}
+
+// 将ini格式的数据解码到v中，v只能是指向结构体的指针。
+// 具体规则可参考Decoder.Decode()。
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// 获取v指向的实际对象，若v是一个空指针，则为其分配内存。
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// 将字符串val转换成v的类型，并保存到v中。
+func setValue(v reflect.Value, val string) error {
+	v = indirect(v)
+
+	if ok, err := unmarshalValue(v, val); ok {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := parseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("不支持的类型%v", v.Type())
+	}
+
+	return nil
+}
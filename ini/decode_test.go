@@ -0,0 +1,685 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert"
+)
+
+type testServer struct {
+	Host    string        `ini:"host"`
+	Port    uint16        `ini:"port"`
+	Timeout time.Duration `ini:"timeout"`
+}
+
+type testConfig struct {
+	Name    string      `ini:"name"`
+	Debug   bool        `ini:"debug"`
+	Count   int         `ini:"count"`
+	Rate    float64     `ini:"rate"`
+	Ptr     *int        `ini:"ptr"`
+	Ignore  string      `ini:"-"`
+	Server  testServer  `ini:"server"`
+	Backend *testServer `ini:"backend"`
+}
+
+type testRemote struct {
+	URL   string `ini:"url"`
+	Fetch string `ini:"fetch"`
+}
+
+type testTreeServer struct {
+	Host string      `ini:"host"`
+	HTTP *testServer `ini:"http"`
+}
+
+type testTree struct {
+	Server  testTreeServer         `ini:"server"`
+	Remotes map[string]*testRemote `ini:"remote"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	a := assert.New(t)
+
+	data := []byte(`
+# comment
+name=app
+debug=true
+count=-5
+rate=0.5
+ptr=7
+Ignore=ignore
+unknown=val
+
+[server]
+host=localhost
+PORT=8080
+timeout=5s
+
+[backend]
+host=127.0.0.1
+
+[unknown]
+key=val
+`)
+	conf := &testConfig{}
+	a.NotError(Unmarshal(data, conf))
+	a.Equal(conf.Name, "app").
+		True(conf.Debug).
+		Equal(conf.Count, -5).
+		Equal(conf.Rate, 0.5).
+		Equal(*conf.Ptr, 7).
+		Equal(conf.Ignore, "")
+	a.Equal(conf.Server, testServer{Host: "localhost", Port: 8080, Timeout: 5 * time.Second})
+	a.Equal(conf.Backend, &testServer{Host: "127.0.0.1"})
+
+	// 无法转换的值
+	a.Error(Unmarshal([]byte("count=abc"), conf))
+	a.Error(Unmarshal([]byte("[server]\nport=-1"), conf))
+	a.Error(Unmarshal([]byte("[server]\ntimeout=5"), conf))
+
+	// 语法错误
+	a.Error(Unmarshal([]byte("[server"), conf))
+
+	// 无效的参数
+	a.Error(Unmarshal(data, *conf))
+	a.Error(Unmarshal(data, nil))
+	i := 5
+	a.Error(Unmarshal(data, &i))
+}
+
+func TestDecoder(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name=app
+[server]
+host=localhost
+PORT=8080
+[unknown]
+key=val
+`
+	// 默认情况下，忽略大小写
+	conf := &testConfig{}
+	a.NotError(NewDecoder(strings.NewReader(data)).Decode(conf))
+	a.Equal(conf.Server.Port, 8080)
+
+	// 严格模式
+	conf = &testConfig{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.Strict()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Name, "app").
+		Equal(conf.Server.Host, "localhost").
+		Equal(conf.Server.Port, 0)
+
+	// 不允许未知的键名
+	dec = NewDecoder(strings.NewReader(data))
+	dec.Strict()
+	dec.DisallowUnknownKeys()
+	err := dec.Decode(&testConfig{})
+	a.Error(err)
+	a.True(strings.Contains(err.Error(), "PORT"))
+
+	// 未知section下的键名不受影响
+	dec = NewDecoder(strings.NewReader(data))
+	dec.DisallowUnknownKeys()
+	a.NotError(dec.Decode(&testConfig{}))
+}
+
+func TestDecoder_DisallowUnknownSections(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name=app
+prot=80
+[server]
+prot=8080
+host=localhost
+[unknown]
+key=val
+[remote]
+url=x
+[remote "origin"]
+url=y
+`
+	conf := &testTree{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.AllowSubsection()
+	dec.DisallowUnknownKeys()
+	dec.DisallowUnknownSections()
+	err := dec.Decode(conf)
+	a.Error(err)
+	a.Equal(conf.Server.Host, "localhost").Equal(conf.Remotes["origin"].URL, "y")
+
+	var errs SyntaxErrors
+	a.True(errors.As(err, &errs)).Equal(len(errs), 5, err)
+	a.Equal(errs[0].Line, 1).True(errors.Is(errs[0], ErrUnknownKey)) // name
+	a.Equal(errs[1].Line, 2).True(errors.Is(errs[1], ErrUnknownKey))
+	a.Equal(errs[2].Line, 4).True(errors.Is(errs[2], ErrUnknownKey))
+	a.Equal(errs[3].Line, 6).True(errors.Is(errs[3], ErrUnknownSection))
+	a.Equal(errs[4].Line, 8).True(errors.Is(errs[4], ErrUnknownSection))
+
+	// 容错模式下与其它错误一起返回
+	dec = NewDecoder(strings.NewReader("[server]\nport=x\n[unknown]"))
+	dec.Tolerant()
+	dec.DisallowUnknownSections()
+	err = dec.Decode(&testConfig{})
+	a.Error(err).True(errors.Is(err, ErrUnknownSection))
+	a.True(strings.Contains(err.Error(), "port"))
+}
+
+func TestDecoder_Decode_required(t *testing.T) {
+	a := assert.New(t)
+
+	type server struct {
+		Host string `ini:"host;required"`
+		Port int    `ini:"port;required"`
+	}
+	type config struct {
+		Name   string  `ini:"name;required"`
+		Debug  bool    `ini:"debug"`
+		Server server  `ini:"server;required"`
+		Admin  *server `ini:"admin"`
+		Cache  *server `ini:"cache;required"`
+	}
+
+	data := `debug=on
+[server]
+host=localhost
+[server]
+port=8080
+[cache]
+`
+	conf := &config{}
+	err := Unmarshal([]byte(data), conf)
+	a.Error(err)
+	a.True(conf.Debug).Equal(conf.Server.Port, 8080).Nil(conf.Admin)
+
+	var errs SyntaxErrors
+	a.True(errors.As(err, &errs)).Equal(len(errs), 3, err)
+	a.Equal(errs[0].Line, 0).True(errors.Is(errs[0], ErrRequired)) // name
+	a.Equal(errs[0].Error(), "encoding/ini，Decode:缺少必需的键名name")
+	a.Equal(errs[1].Line, 0).True(errors.Is(errs[1], ErrRequired)) // cache.host
+	a.Equal(errs[2].Line, 0).True(errors.Is(errs[2], ErrRequired)) // cache.port
+	var rerr *RequiredError
+	a.True(errors.As(errs[2], &rerr)).Equal(rerr, &RequiredError{Section: "cache", Key: "port"})
+	a.Equal(errs[2].Error(), "encoding/ini，Decode:[cache]中缺少必需的键名port")
+
+	// 缺少section
+	err = Unmarshal([]byte("name=app"), conf)
+	errs = nil
+	a.True(errors.As(err, &errs)).Equal(len(errs), 2, err)
+	a.True(strings.Contains(errs[0].Msg, "[server]"))
+	a.True(strings.Contains(errs[1].Msg, "[cache]"))
+	rerr = nil
+	a.True(errors.As(errs[1], &rerr)).Equal(rerr, &RequiredError{Section: "cache"})
+
+	a.NotError(Unmarshal([]byte("name=app\n"+data+"host=h\nport=1\n[admin]\nname=app"), &struct {
+		Name  string `ini:"name;required"`
+		Admin *struct {
+			Name string `ini:"name;required"`
+		} `ini:"admin;required"`
+	}{}))
+}
+
+func TestDecoder_SetDuplicate(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name = app1
+name = app2
+paths = /usr
+paths[] = /opt
+[server]
+port = 80
+[server]
+port = 8080
+`
+	type config struct {
+		Name   string     `ini:"name"`
+		Paths  []string   `ini:"paths"`
+		Server testServer `ini:"server"`
+	}
+
+	conf := &config{Paths: []string{"default"}}
+	a.NotError(Unmarshal([]byte(data), conf))
+	a.Equal(conf, &config{
+		Name:   "app2",
+		Paths:  []string{"/usr", "/opt"},
+		Server: testServer{Port: 8080},
+	})
+
+	conf = &config{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.SetDuplicate(DuplicateFirst)
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Name, "app1").
+		Equal(conf.Paths, []string{"/usr", "/opt"}).
+		Equal(conf.Server.Port, 80)
+
+	for _, d := range []Duplicate{DuplicateError, DuplicateList} {
+		dec = NewDecoder(strings.NewReader(data))
+		dec.SetDuplicate(d)
+		err := dec.Decode(&config{})
+		a.Error(err)
+		a.True(strings.Contains(err.Error(), "第2行"))
+	}
+
+	// 重复的section
+	dec = NewDecoder(strings.NewReader(data[strings.Index(data, "[server]"):]))
+	dec.SetDuplicate(DuplicateError)
+	err := dec.Decode(&config{})
+	a.Error(err)
+	a.True(strings.Contains(err.Error(), "第3行"))
+
+	// 切片元素的类型错误
+	a.Error(Unmarshal([]byte("ports=1\nports=x"), &struct{ Ports []int }{}))
+}
+
+func TestDecoder_Tolerant(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name = app
+count = x
+invalid
+[server]
+port = 99999
+host = localhost
+unknown = 1
+`
+
+	// 默认在第一个错误处返回
+	err := Unmarshal([]byte(data), &testConfig{})
+	a.Error(err)
+	serr, ok := err.(*SyntaxError)
+	a.True(ok).Equal(serr.Line, 3)
+
+	conf := &testConfig{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.Tolerant()
+	dec.DisallowUnknownKeys()
+	err = dec.Decode(conf)
+	a.Error(err)
+	a.Equal(conf.Name, "app").
+		Equal(conf.Count, 0).
+		Equal(conf.Server.Host, "localhost").
+		Equal(conf.Server.Port, 0)
+
+	serr = nil
+	a.True(errors.As(err, &serr)).Equal(serr.Line, 3)
+	a.True(errors.Is(err, ErrMissingDelimiter))
+	msg := err.Error()
+	a.True(strings.Contains(msg, "count")).
+		True(strings.Contains(msg, "port")).
+		True(strings.Contains(msg, "unknown"))
+
+	// 没有错误
+	dec = NewDecoder(strings.NewReader("name=app"))
+	dec.Tolerant()
+	a.NotError(dec.Decode(&testConfig{}))
+}
+
+func TestDecoder_AllowSubsection(t *testing.T) {
+	a := assert.New(t)
+
+	data := `[server]
+host = localhost
+[server.http]
+port = 8080
+[remote "origin"]
+url = git@example.com
+[remote "upstream"]
+url = https://example.com
+[remote "origin"]
+fetch = +refs/heads/*
+[remote]
+url = ignore
+[unknown.section]
+key = val
+`
+
+	// 默认不解析层级路径
+	conf := &testTree{}
+	a.NotError(Unmarshal([]byte(data), conf))
+	a.Equal(conf.Server.Host, "localhost").
+		Nil(conf.Server.HTTP).
+		Nil(conf.Remotes)
+
+	conf = &testTree{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf, &testTree{
+		Server: testTreeServer{
+			Host: "localhost",
+			HTTP: &testServer{Port: 8080},
+		},
+		Remotes: map[string]*testRemote{
+			"origin":   &testRemote{URL: "git@example.com", Fetch: "+refs/heads/*"},
+			"upstream": &testRemote{URL: "https://example.com"},
+		},
+	})
+
+	// 值类型的map，以及与完整名称相同的字段优先
+	type remote struct {
+		URL string `ini:"url"`
+	}
+	v := &struct {
+		Remotes map[string]remote `ini:"remote"`
+		Origin  remote            `ini:"remote \"origin\""`
+	}{}
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(v))
+	a.Equal(v.Origin.URL, "git@example.com").
+		Equal(v.Remotes, map[string]remote{"upstream": remote{URL: "https://example.com"}})
+
+	// 无效的section名称
+	dec = NewDecoder(strings.NewReader("[a..b]"))
+	dec.AllowSubsection()
+	a.Error(dec.Decode(&testTree{}))
+}
+
+func TestDecoder_Interpolate(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name = app
+[server]
+host = ${name}.example.com
+port = 80
+[backend]
+host = b%(port)s.${server.host}
+port = 81
+port = 8080
+`
+
+	// 默认不进行变量替换
+	conf := &testConfig{}
+	a.NotError(NewDecoder(strings.NewReader(data)).Decode(conf))
+	a.Equal(conf.Server.Host, "${name}.example.com")
+
+	conf = &testConfig{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.Interpolate(nil)
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Server.Host, "app.example.com").
+		Equal(conf.Backend.Host, "b8080.app.example.com").
+		Equal(conf.Backend.Port, 8080)
+
+	// 循环引用
+	dec = NewDecoder(strings.NewReader("a=${b}\nb=${a}"))
+	dec.Interpolate(nil)
+	a.Error(dec.Decode(conf))
+}
+
+func TestDecoder_AllowInheritance(t *testing.T) {
+	a := assert.New(t)
+
+	data := `[defaults]
+port = 80
+timeout = 5s
+[server : defaults]
+host = example.com
+[backend : server]
+port = 8080
+`
+
+	// 默认不处理继承
+	conf := &testConfig{}
+	a.NotError(NewDecoder(strings.NewReader(data)).Decode(conf))
+	a.Equal(conf.Server.Host, "").Nil(conf.Backend)
+
+	conf = &testConfig{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.AllowInheritance()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Server, testServer{Host: "example.com", Port: 80, Timeout: 5 * time.Second})
+	a.Equal(conf.Backend, &testServer{Host: "example.com", Port: 8080, Timeout: 5 * time.Second})
+
+	// 与SetDuplicate(DuplicateError)一起使用，继承的键名不算重复。
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowInheritance()
+	dec.SetDuplicate(DuplicateError)
+	a.NotError(dec.Decode(&testConfig{}))
+
+	// 循环继承
+	dec = NewDecoder(strings.NewReader("[server : backend]\n[backend : server]\n"))
+	dec.AllowInheritance()
+	err := dec.Decode(&testConfig{})
+	a.Error(err).True(strings.Contains(err.Error(), "backend -> server -> backend"))
+
+	// 父section不存在
+	dec = NewDecoder(strings.NewReader("[server : none]\nhost = x\n"))
+	dec.AllowInheritance()
+	a.Error(dec.Decode(&testConfig{}))
+
+	// 容错模式下继续解码
+	conf = &testConfig{}
+	dec = NewDecoder(strings.NewReader("[server : none]\nhost = x\n"))
+	dec.AllowInheritance()
+	dec.Tolerant()
+	a.Error(dec.Decode(conf))
+	a.Equal(conf.Server.Host, "x")
+
+	// 先继承再进行变量替换，继承的键值以子section的内容进行替换
+	data = `[defaults]
+host = a
+port = 80
+timeout = ${port}s
+[server : defaults]
+port = 8
+[backend : defaults]
+host = h${port}.example.com
+`
+	conf = &testConfig{}
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowInheritance()
+	dec.Interpolate(nil)
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Server, testServer{Host: "a", Port: 8, Timeout: 8 * time.Second})
+	a.Equal(conf.Backend, &testServer{Host: "h80.example.com", Port: 80, Timeout: 80 * time.Second})
+}
+
+type testDefaultServer struct {
+	Host  string   `ini:"host;default(localhost);comment(监听的地址)"`
+	Port  int      `ini:"port;default(8080);comment(监听的端口)"`
+	Paths []string `ini:"paths;default(/usr,/opt)"`
+}
+
+type testDefaultConfig struct {
+	Name    string                        `ini:"name;default(app)"`
+	Debug   *bool                         `ini:"debug;default(on)"`
+	Server  testDefaultServer             `ini:"server;comment(服务器设置)"`
+	Backend *testDefaultServer            `ini:"backend"`
+	Remotes map[string]*testDefaultServer `ini:"remote;comment(远程服务器)"`
+}
+
+func TestDecoder_Decode_default(t *testing.T) {
+	a := assert.New(t)
+
+	conf := &testDefaultConfig{}
+	a.NotError(Unmarshal(nil, conf))
+	a.Equal(conf.Name, "app").True(*conf.Debug)
+	a.Equal(conf.Server, testDefaultServer{Host: "localhost", Port: 8080, Paths: []string{"/usr", "/opt"}})
+	a.Nil(conf.Backend).Nil(conf.Remotes) // 空指针的section不会因为默认值而分配内存
+
+	data := `name=
+[server]
+port=0
+paths=/bin
+[backend]
+port=81
+[remote "origin"]
+host=example.com
+`
+	conf = &testDefaultConfig{Name: "preset"}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Name, "").True(*conf.Debug)
+	a.Equal(conf.Server, testDefaultServer{Host: "localhost", Port: 0, Paths: []string{"/bin"}})
+	a.Equal(conf.Backend, &testDefaultServer{Host: "localhost", Port: 81, Paths: []string{"/usr", "/opt"}})
+	a.Equal(conf.Remotes["origin"], &testDefaultServer{Host: "example.com", Port: 8080, Paths: []string{"/usr", "/opt"}})
+
+	// 已有的值不会被默认值覆盖
+	conf = &testDefaultConfig{Name: "preset", Server: testDefaultServer{Port: 1}}
+	a.NotError(Unmarshal(nil, conf))
+	a.Equal(conf.Name, "preset").Equal(conf.Server.Port, 1)
+
+	// 无效的默认值
+	err := Unmarshal(nil, &struct {
+		Port int `ini:"port;default(abc)"`
+	}{})
+	a.Error(err).True(strings.Contains(err.Error(), "port"))
+
+	// 空的默认值与没有默认值相同
+	obj := &struct {
+		Port int    `ini:"port;default()"`
+		Name string `ini:"name;default()"`
+	}{}
+	a.NotError(Unmarshal([]byte("name=n"), obj))
+	a.Equal(obj.Port, 0).Equal(obj.Name, "n")
+
+	// 注释中包含括号
+	err = Unmarshal(nil, &struct {
+		Port int `ini:"port;comment(Listening port (TCP))"`
+	}{})
+	a.Error(err).True(strings.Contains(err.Error(), "port"))
+}
+
+type testRepeated struct {
+	Name    string        `ini:"name"`
+	Servers []testServer  `ini:"server"`
+	Ptrs    []*testServer `ini:"ptr"`
+	Tree    struct {
+		Nodes []testRemote `ini:"node"`
+	} `ini:"tree"`
+}
+
+func TestDecoder_AllowRepeatedSections(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name=app
+[server]
+host=a
+port=80
+[ptr]
+host=p
+[server]
+host=b
+[other]
+key=1
+[other]
+key=2
+[server]
+host=c
+port=82
+`
+	// 默认合并同名的section
+	conf := &testRepeated{Servers: []testServer{{Host: "old"}}}
+	a.NotError(Unmarshal([]byte(data), conf))
+	a.Equal(conf.Servers, []testServer{{Host: "c", Port: 82}})
+
+	conf = &testRepeated{Servers: []testServer{{Host: "old"}}}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.AllowRepeatedSections()
+	dec.SetDuplicate(DuplicateError) // 重复的section不受影响
+	err := dec.Decode(conf)
+	a.Error(err).True(strings.Contains(err.Error(), "other"))
+
+	conf = &testRepeated{Servers: []testServer{{Host: "old"}}}
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowRepeatedSections()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Name, "app")
+	a.Equal(conf.Servers, []testServer{{Host: "a", Port: 80}, {Host: "b"}, {Host: "c", Port: 82}})
+	a.Equal(conf.Ptrs, []*testServer{{Host: "p"}})
+
+	// 子section
+	data = `[tree.node]
+url=1
+[tree.node]
+url=2
+[tree.node.sub]
+url=3
+`
+	conf = &testRepeated{}
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowRepeatedSections()
+	dec.AllowSubsection()
+	dec.DisallowUnknownSections()
+	err = dec.Decode(conf)
+	var errs SyntaxErrors
+	a.True(errors.As(err, &errs)).Equal(len(errs), 1).Equal(errs[0].Line, 5)
+	a.Equal(conf.Tree.Nodes, []testRemote{{URL: "1"}, {URL: "2"}})
+
+	// required和默认值
+	type item struct {
+		Name string `ini:"name;required"`
+		Port int    `ini:"port;default(80)"`
+	}
+	obj := &struct {
+		Items []item `ini:"item;required"`
+	}{}
+	dec = NewDecoder(strings.NewReader("[item]\nname=a\n[item]\nport=81"))
+	dec.AllowRepeatedSections()
+	err = dec.Decode(obj)
+	errs = nil
+	a.True(errors.As(err, &errs)).Equal(len(errs), 1).Equal(errs[0].Line, 0)
+	a.True(errors.Is(errs[0], ErrRequired))
+	a.Equal(obj.Items, []item{{Name: "a", Port: 80}, {Port: 81}})
+
+	errs = nil
+	a.True(errors.As(Unmarshal(nil, obj), &errs)).Equal(len(errs), 1).Equal(errs[0].Line, 0)
+
+	// 与继承一起使用，重复的section不能参与继承
+	data = `[base]
+port=80
+[ptr : base]
+host=p
+[server]
+host=a
+[server]
+host=b
+`
+	conf = &testRepeated{}
+	dec = NewDecoder(strings.NewReader(data))
+	dec.AllowRepeatedSections()
+	dec.AllowInheritance()
+	a.NotError(dec.Decode(conf))
+	a.Equal(conf.Ptrs, []*testServer{{Host: "p", Port: 80}})
+	a.Equal(conf.Servers, []testServer{{Host: "a"}, {Host: "b"}})
+
+	dec = NewDecoder(strings.NewReader("[base]\nport=80\n[server : base]\nhost=a\n[server : base]\nhost=b\n"))
+	dec.AllowRepeatedSections()
+	dec.AllowInheritance()
+	err = dec.Decode(&testRepeated{})
+	a.Error(err).True(strings.Contains(err.Error(), "server"))
+
+	dec = NewDecoder(strings.NewReader("[server]\nport=80\n[server]\nport=81\n[ptr : server]\nhost=p\n"))
+	dec.AllowRepeatedSections()
+	dec.AllowInheritance()
+	err = dec.Decode(&testRepeated{})
+	a.Error(err).True(strings.Contains(err.Error(), "server"))
+}
+
+func TestSetValue(t *testing.T) {
+	a := assert.New(t)
+
+	var b bool
+	for _, val := range []string{"true", "Yes", "ON", "1", "t"} {
+		a.NotError(setValue(reflect.ValueOf(&b).Elem(), val))
+		a.True(b, val)
+	}
+	for _, val := range []string{"false", "no", "Off", "0", "F"} {
+		a.NotError(setValue(reflect.ValueOf(&b).Elem(), val))
+		a.False(b, val)
+	}
+	a.Error(setValue(reflect.ValueOf(&b).Elem(), "maybe"))
+}
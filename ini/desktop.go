@@ -0,0 +1,418 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// .desktop文件中必须存在的group名称，且必须是第一个group。
+const DesktopEntryGroup = "Desktop Entry"
+
+// 规范中定义的布尔类型的键名，Validate()会检测其值是否合法。
+var desktopBoolKeys = []string{
+	"NoDisplay", "Hidden", "DBusActivatable", "Terminal",
+	"StartupNotify", "PrefersNonDefaultGPU", "SingleMainWindow",
+}
+
+// DesktopEntry表示一个freedesktop的.desktop文件。
+//
+// 具体格式可参考https://specifications.freedesktop.org/desktop-entry-spec/latest/
+type DesktopEntry struct {
+	Groups []*DesktopGroup
+}
+
+// DesktopGroup表示.desktop文件中的一个group，即普通ini中的section。
+type DesktopGroup struct {
+	Name string
+	Line int // group声明所在的行号
+	Keys []*DesktopKey
+}
+
+// DesktopKey表示一个键值对，本地化的键名，比如Name[de_DE]，
+// 被拆分成键名和locale两部分。
+type DesktopKey struct {
+	Name   string // 不包含locale的键名
+	Locale string // 去掉encoding部分的locale，未本地化的键值为空
+	Value  string // 未处理转义字符的原始内容
+	Line   int    // 所在的行号
+}
+
+// 从r中读取.desktop文件的内容。
+//
+// 除了基本的语法之外，还会检测以下规则，所有的错误以SyntaxErrors的形式一起返回：
+// 第一个group必须是[Desktop Entry]，且之前只能有注释和空行；
+// group名称不能包含`[`、`]`和控制字符，也不能重复；
+// 键名只能包含字母、数字和`-`，同一group下不能有重复的键名。
+//
+// 必需的键名和键值类型等内容，需要调用DesktopEntry.Validate()检测。
+func ParseDesktopEntry(r io.Reader) (*DesktopEntry, error) {
+	rd := NewReader(r, DesktopDialect)
+	rd.Tolerant()
+
+	e := &DesktopEntry{Groups: []*DesktopGroup{}}
+	var curr *DesktopGroup
+	grouped := false // 是否已经出现过group声明，无论其是否有效
+	for {
+		token, err := rd.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Type {
+		case EOF:
+			return e, nil
+		case Section:
+			grouped = true
+			switch {
+			case !isDesktopGroup(token.Value):
+				curr = nil
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w%v", ErrInvalidSectionName, token.Value)))
+			case e.Group(token.Value) != nil:
+				curr = nil
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w%v", ErrDuplicateSection, token.Value)))
+			case len(e.Groups) == 0 && token.Value != DesktopEntryGroup:
+				curr = nil
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w：第一个group必须是%v", ErrInvalidSectionName, DesktopEntryGroup)))
+			default:
+				curr = &DesktopGroup{Name: token.Value, Line: token.Start.Line, Keys: []*DesktopKey{}}
+				e.Groups = append(e.Groups, curr)
+			}
+		case Element:
+			if !grouped {
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w%v：键值对必须在group之中", ErrInvalidKey, token.Key)))
+				continue
+			}
+			if curr == nil { // 所在的group无效，错误已经报告过了。
+				continue
+			}
+
+			name, locale, ok := splitDesktopKey(token.Key)
+			if !ok {
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w%v", ErrInvalidKey, token.Key)))
+				continue
+			}
+			if curr.Key(name, locale) != nil {
+				rd.report(rd.newSyntaxError(token.Start.Column, "ParseDesktopEntry", fmt.Errorf("%w%v", ErrDuplicateKey, token.Key)))
+				continue
+			}
+
+			curr.Keys = append(curr.Keys, &DesktopKey{
+				Name:   name,
+				Locale: locale,
+				Value:  token.Value,
+				Line:   token.Start.Line,
+			})
+		}
+	}
+}
+
+// group名称是否合法，只能包含除`[`和`]`之外的可打印ASCII字符。
+func isDesktopGroup(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 0x20 || c > 0x7e || c == '[' || c == ']' {
+			return false
+		}
+	}
+
+	return len(name) > 0
+}
+
+// 将Name[de_DE]形式的键名拆分成键名和locale两部分，
+// 键名不合法时，第三个返回值为false。
+func splitDesktopKey(key string) (name, locale string, ok bool) {
+	name = key
+	if i := strings.IndexByte(key, '['); i > -1 {
+		if !strings.HasSuffix(key, "]") || i+2 >= len(key) {
+			return "", "", false
+		}
+		name, locale = key[:i], normalizeLocale(key[i+1:len(key)-1])
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return "", "", false
+		}
+	}
+
+	return name, locale, len(name) > 0
+}
+
+// 将locale拆分成lang、country和modifier三部分，encoding部分被忽略，
+// 比如sr_YU.UTF-8@Latn拆分成sr、YU和Latn。
+func splitLocale(locale string) (lang, country, modifier string) {
+	if i := strings.IndexByte(locale, '@'); i > -1 {
+		locale, modifier = locale[:i], locale[i+1:]
+	}
+	if i := strings.IndexByte(locale, '.'); i > -1 {
+		locale = locale[:i]
+	}
+	if i := strings.IndexByte(locale, '_'); i > -1 {
+		locale, country = locale[:i], locale[i+1:]
+	}
+
+	return locale, country, modifier
+}
+
+// 去掉locale中的encoding部分。
+func normalizeLocale(locale string) string {
+	lang, country, modifier := splitLocale(locale)
+	if len(country) > 0 {
+		lang += "_" + country
+	}
+	if len(modifier) > 0 {
+		lang += "@" + modifier
+	}
+	return lang
+}
+
+// 按规范中的顺序返回locale需要依次匹配的内容，最后一个为空，表示未本地化的键值：
+//  lang_COUNTRY@MODIFIER
+//  lang_COUNTRY
+//  lang@MODIFIER
+//  lang
+func localeCandidates(locale string) []string {
+	lang, country, modifier := splitLocale(locale)
+	if len(lang) == 0 {
+		return []string{""}
+	}
+
+	ret := make([]string, 0, 5)
+	if len(country) > 0 && len(modifier) > 0 {
+		ret = append(ret, lang+"_"+country+"@"+modifier)
+	}
+	if len(country) > 0 {
+		ret = append(ret, lang+"_"+country)
+	}
+	if len(modifier) > 0 {
+		ret = append(ret, lang+"@"+modifier)
+	}
+	return append(ret, lang, "")
+}
+
+// 查找名称为name的group，不存在则返回nil。
+func (e *DesktopEntry) Group(name string) *DesktopGroup {
+	for _, g := range e.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// 检测[Desktop Entry]中的内容是否符合规范，所有的错误以SyntaxErrors的形式一起返回：
+// Type和Name必须存在，Type只能是Application、Link或是Directory；
+// Type为Link时，URL必须存在；Type为Application且DBusActivatable不为true时，Exec必须存在；
+// 规范中定义的布尔类型的键值只能是true或是false。
+func (e *DesktopEntry) Validate() error {
+	g := e.Group(DesktopEntryGroup)
+	if g == nil {
+		return SyntaxErrors{&SyntaxError{Msg: "Validate:缺少" + DesktopEntryGroup}}
+	}
+
+	var errs SyntaxErrors
+	report := func(line int, format string, v ...interface{}) {
+		errs = append(errs, &SyntaxError{Line: line, Msg: "Validate:" + fmt.Sprintf(format, v...)})
+	}
+
+	required := func(name string) *DesktopKey {
+		k := g.Key(name, "")
+		if k == nil {
+			report(g.Line, "缺少必需的键名%v", name)
+		}
+		return k
+	}
+
+	required("Name")
+	if typ := required("Type"); typ != nil {
+		switch typ.Value {
+		case "Application":
+			if k := g.Key("DBusActivatable", ""); k == nil || k.Value != "true" {
+				required("Exec")
+			}
+		case "Link":
+			required("URL")
+		case "Directory":
+		default:
+			report(typ.Line, "无效的Type值%v", typ.Value)
+		}
+	}
+
+	for _, name := range desktopBoolKeys {
+		if k := g.Key(name, ""); k != nil && k.Value != "true" && k.Value != "false" {
+			report(k.Line, "%v的值只能是true或是false", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// 查找键名为name且locale完全相同的键值对，不存在则返回nil。
+// locale中的encoding部分会被忽略。
+func (g *DesktopGroup) Key(name, locale string) *DesktopKey {
+	locale = normalizeLocale(locale)
+	for _, k := range g.Keys {
+		if k.Name == name && k.Locale == locale {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// 按规范中的规则查找与locale最匹配的键值对，都不匹配时返回未本地化的键值对，
+// 不存在则返回nil。locale的格式为lang_COUNTRY.ENCODING@MODIFIER，除lang之外都可以省略。
+func (g *DesktopGroup) LocaleKey(name, locale string) *DesktopKey {
+	for _, l := range localeCandidates(locale) {
+		if k := g.Key(name, l); k != nil {
+			return k
+		}
+	}
+
+	return nil
+}
+
+// 获取未本地化的字符串类型键值，会处理其中的转义字符。
+func (g *DesktopGroup) String(name string) (string, bool) {
+	return g.LocaleString(name, "")
+}
+
+// 获取与locale最匹配的字符串类型键值，会处理其中的转义字符。
+func (g *DesktopGroup) LocaleString(name, locale string) (string, bool) {
+	k := g.LocaleKey(name, locale)
+	if k == nil {
+		return "", false
+	}
+	return unescapeDesktop(k.Value), true
+}
+
+// 获取未本地化的列表类型键值，列表以`;`分隔，元素中的`;`以`\;`表示。
+func (g *DesktopGroup) Strings(name string) ([]string, bool) {
+	return g.LocaleStrings(name, "")
+}
+
+// 获取与locale最匹配的列表类型键值，列表以`;`分隔，元素中的`;`以`\;`表示。
+func (g *DesktopGroup) LocaleStrings(name, locale string) ([]string, bool) {
+	k := g.LocaleKey(name, locale)
+	if k == nil {
+		return nil, false
+	}
+	return splitDesktopList(k.Value), true
+}
+
+// 获取布尔类型的键值，只能是true或是false，其它值都将返回错误信息。
+func (g *DesktopGroup) Bool(name string) (bool, error) {
+	k := g.Key(name, "")
+	if k == nil {
+		return false, fmt.Errorf("Bool:[%v]中不存在%v", g.Name, name)
+	}
+
+	switch k.Value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("Bool:[%v]中%v的值%v只能是true或是false", g.Name, name, k.Value)
+}
+
+// 获取数值类型的键值。
+func (g *DesktopGroup) Number(name string) (float64, error) {
+	k := g.Key(name, "")
+	if k == nil {
+		return 0, fmt.Errorf("Number:[%v]中不存在%v", g.Name, name)
+	}
+
+	n, err := strconv.ParseFloat(k.Value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Number:[%v]中%v的值无法转换成数值：%v", g.Name, name, err)
+	}
+	return n, nil
+}
+
+// 处理字符串中的转义字符：\s、\n、\t、\r、\\以及列表中的\;，
+// 其它的`\`原样保留。
+func unescapeDesktop(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 's':
+				c = ' '
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			case '\\', ';':
+				c = s[i]
+			default:
+				i--
+			}
+		}
+		buf = append(buf, c)
+	}
+
+	return string(buf)
+}
+
+// 将以`;`分隔的列表拆分成字符串数组，最后的`;`可以省略。
+func splitDesktopList(s string) []string {
+	vals := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ';':
+			vals = append(vals, unescapeDesktop(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	if start < len(s) {
+		vals = append(vals, unescapeDesktop(s[start:]))
+	}
+	return vals
+}
+
+// 按.desktop文件的规则转义字符串，返回的内容可以直接通过DesktopDialect写入。
+func EscapeDesktopString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	s = r.Replace(s)
+
+	// 首尾的空格在读取时会被忽略
+	if strings.HasPrefix(s, " ") {
+		s = `\s` + s[1:]
+	}
+	if len(s) > 1 && strings.HasSuffix(s, " ") {
+		s = s[:len(s)-1] + `\s`
+	}
+	return s
+}
+
+// 将vals转换成以`;`分隔的列表，返回的内容可以直接通过DesktopDialect写入。
+func JoinDesktopList(vals ...string) string {
+	var b strings.Builder
+	for _, v := range vals {
+		b.WriteString(strings.Replace(EscapeDesktopString(v), ";", `\;`, -1))
+		b.WriteByte(';')
+	}
+	return b.String()
+}
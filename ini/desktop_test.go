@@ -0,0 +1,205 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+const desktopTestData = `# comment
+[Desktop Entry]
+Type=Application
+Name=Foo Viewer
+Name[de]=Foo Betrachter
+Name[de_DE]=Foo Betrachter (DE)
+Name[sr@Latn]=Foo pregledač
+Name[sr_YU.UTF-8]=Foo прегледач
+Comment=\sview\tfoo\\files\n
+Exec="/opt/foo bar/bin/foo" %U
+Terminal=false
+Keywords=foo;view\;er;
+Keywords[de]=foo;betrachter
+Version=1.5
+
+[Desktop Action New]
+Name=New Window
+Exec=foo --new-window
+`
+
+func TestParseDesktopEntry(t *testing.T) {
+	a := assert.New(t)
+
+	e, err := ParseDesktopEntry(strings.NewReader(desktopTestData))
+	a.NotError(err).NotNil(e)
+	a.NotError(e.Validate())
+	a.Equal(len(e.Groups), 2)
+
+	g := e.Group(DesktopEntryGroup)
+	a.NotNil(g).Equal(g.Line, 2)
+	a.Equal(g.Key("Name", "sr_YU").Value, "Foo прегледач").
+		Equal(g.Key("Name", "sr_YU.UTF-8").Line, 8)
+	a.Nil(g.Key("Name", "fr"))
+
+	val, found := g.String("Exec")
+	a.True(found).Equal(val, `"/opt/foo bar/bin/foo" %U`)
+	val, found = g.String("Comment")
+	a.True(found).Equal(val, " view\tfoo\\files\n")
+	_, found = g.String("Icon")
+	a.False(found)
+
+	list, found := g.Strings("Keywords")
+	a.True(found).Equal(list, []string{"foo", "view;er"})
+	list, found = g.LocaleStrings("Keywords", "de_AT.UTF-8")
+	a.True(found).Equal(list, []string{"foo", "betrachter"})
+
+	b, err := g.Bool("Terminal")
+	a.NotError(err).False(b)
+	_, err = g.Bool("NoDisplay")
+	a.Error(err)
+
+	n, err := g.Number("Version")
+	a.NotError(err).Equal(n, 1.5)
+	_, err = g.Number("Name")
+	a.Error(err)
+
+	action := e.Group("Desktop Action New")
+	a.NotNil(action)
+	val, _ = action.LocaleString("Name", "de_DE")
+	a.Equal(val, "New Window")
+}
+
+func TestDesktopGroup_LocaleString(t *testing.T) {
+	a := assert.New(t)
+
+	e, err := ParseDesktopEntry(strings.NewReader(desktopTestData))
+	a.NotError(err).NotNil(e)
+	g := e.Group(DesktopEntryGroup)
+
+	data := map[string]string{
+		"":              "Foo Viewer",
+		"C":             "Foo Viewer",
+		"fr_FR":         "Foo Viewer",
+		"de":            "Foo Betrachter",
+		"de_AT":         "Foo Betrachter",
+		"de_DE":         "Foo Betrachter (DE)",
+		"de_DE.UTF-8":   "Foo Betrachter (DE)",
+		"de_DE@euro":    "Foo Betrachter (DE)",
+		"sr_YU@Latn":    "Foo прегледач",
+		"sr_RS@Latn":    "Foo pregledač",
+		"sr@Latn":       "Foo pregledač",
+		"sr_RS":         "Foo Viewer",
+		"sr_YU.UTF-8@x": "Foo прегледач",
+	}
+	for locale, want := range data {
+		val, found := g.LocaleString("Name", locale)
+		a.True(found, locale).Equal(val, want, locale)
+	}
+
+	a.Equal(localeCandidates("sr_YU.UTF-8@Latn"), []string{"sr_YU@Latn", "sr_YU", "sr@Latn", "sr", ""})
+	a.Equal(localeCandidates(""), []string{""})
+}
+
+func TestParseDesktopEntry_error(t *testing.T) {
+	a := assert.New(t)
+
+	e, err := ParseDesktopEntry(strings.NewReader(`Key=before group
+[Other]
+Name=x
+[Desktop Entry]
+Name=Foo
+Name=Bar
+Na_me=x
+Name[=x
+[Desktop Entry]
+Type=Link
+[A[b]]
+Key=v
+`))
+	a.Error(err).Nil(e)
+
+	var errs SyntaxErrors
+	a.True(errors.As(err, &errs))
+	lines := make([]int, 0, len(errs))
+	for _, serr := range errs {
+		lines = append(lines, serr.Line)
+	}
+	a.Equal(lines, []int{1, 2, 6, 7, 8, 9, 11})
+	a.True(errors.Is(errs[0], ErrInvalidKey)).
+		True(errors.Is(errs[1], ErrInvalidSectionName)).
+		True(errors.Is(errs[2], ErrDuplicateKey)).
+		True(errors.Is(errs[5], ErrDuplicateSection))
+}
+
+func TestDesktopEntry_Validate(t *testing.T) {
+	a := assert.New(t)
+
+	validate := func(data string) []int {
+		e, err := ParseDesktopEntry(strings.NewReader(data))
+		a.NotError(err).NotNil(e)
+
+		err = e.Validate()
+		if err == nil {
+			return nil
+		}
+
+		var errs SyntaxErrors
+		a.True(errors.As(err, &errs))
+		lines := make([]int, 0, len(errs))
+		for _, serr := range errs {
+			lines = append(lines, serr.Line)
+		}
+		return lines
+	}
+
+	a.Equal(validate(""), []int{0})
+	a.Equal(validate("[Desktop Entry]\n"), []int{1, 1})
+	a.Equal(validate("[Desktop Entry]\nName=x\nType=Link\n"), []int{1})
+	a.Nil(validate("[Desktop Entry]\nName=x\nType=Link\nURL=https://example.com\n"))
+	a.Equal(validate("[Desktop Entry]\nName=x\nType=Application\n"), []int{1})
+	a.Nil(validate("[Desktop Entry]\nName=x\nType=Application\nDBusActivatable=true\n"))
+	a.Equal(validate("[Desktop Entry]\nName=x\nType=Unknown\nHidden=yes\n"), []int{3, 4})
+	a.Nil(validate("[Desktop Entry]\nName=x\nType=Directory\n"))
+}
+
+func TestWriter_Desktop(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0, DesktopDialect)
+	a.NotError(err)
+	a.NotError(w.AddSection(DesktopEntryGroup))
+	a.NotError(w.AddElement("Type", "Application"))
+	a.NotError(w.AddElement("Name[de_DE]", EscapeDesktopString(" Foo\t\\Bar ")))
+	a.NotError(w.AddElement("Keywords", JoinDesktopList("a;b", "c\nd")))
+	a.NotError(w.AddElement("Exec", `"/opt/foo bar/bin/foo" %U`))
+	a.Error(w.AddElement("Comment", "a\nb"))
+	a.Error(w.AddElement("#k", ""))
+	a.Error(w.AddElement("[k]", ""))
+	a.Error(w.AddElement(`"k"`, ""))
+	a.Error(w.AddElementComment("Comment", "a", "b"))
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), `[Desktop Entry]
+Type=Application
+Name[de_DE]=\sFoo\t\\Bar\s
+Keywords=a\;b;c\nd;
+Exec="/opt/foo bar/bin/foo" %U
+`)
+
+	e, err := ParseDesktopEntry(buf)
+	a.NotError(err).NotNil(e)
+	g := e.Group(DesktopEntryGroup)
+	val, _ := g.LocaleString("Name", "de_DE")
+	a.Equal(val, " Foo\t\\Bar ")
+	list, _ := g.Strings("Keywords")
+	a.Equal(list, []string{"a;b", "c\nd"})
+	val, _ = g.String("Exec")
+	a.Equal(val, `"/opt/foo bar/bin/foo" %U`)
+}
@@ -0,0 +1,208 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Dialect用于描述ini格式的各种变体，可以传递给NewReader()和NewWriter()等函数。
+//
+// 除GitDialect、SystemdDialect和DesktopDialect之外，无论使用哪种Dialect，引号、`"""`多行内容以及以`\`结尾的续行都依然有效。
+//
+// 空白字符的处理仅能通过IndentContinuation和SpaceAroundDelimiter调整，
+// 其它情况下都与Reader的默认规则相同：键名、键值和section名称都会去掉首尾的空白字符，
+// 需要保留时可以使用引号。
+type Dialect struct {
+	// 键名与键值之间的分隔符，读取时以最先出现的分隔符为准，写入时使用第一个分隔符。
+	// 为空时使用`=`。
+	Delimiters []string
+
+	// 注释的前缀，比如`#`、`;`或是`//`，写入时默认使用第一个前缀。
+	// 为空时使用`#`和`;`。
+	CommentPrefixes []string
+
+	// 是否支持行尾注释，与Reader.AllowInlineComment()相同。
+	InlineComment bool
+
+	// 是否将键名转换成小写，用于大小写无关的键名。
+	LowerKeys bool
+
+	// 是否将section名称转换成小写，用于大小写无关的section名称。
+	// 引号中的子section名称，比如[remote "Origin"]中的Origin，不受影响。
+	LowerSections bool
+
+	// 是否将以空白字符开头的行作为上一个键值的续行，以换行符与之前的内容合并，
+	// 与Python configparser的行为相同：
+	//  key = line 1
+	//      line 2
+	// 写入时，多行内容也将以这种形式输出。
+	IndentContinuation bool
+
+	// 是否允许没有分隔符的键名，其键值为NoValue。
+	AllowNoValue bool
+	NoValue      string
+
+	// 写入时，是否在分隔符的两边各添加一个空格。
+	SpaceAroundDelimiter bool
+
+	// 键值的引号、转义字符和续行的处理规则，仅预定义的Dialect使用。
+	style valueStyle
+}
+
+// 键值的处理规则
+type valueStyle int
+
+const (
+	styleDefault valueStyle = iota
+	styleGit                // git config的规则，由GitDialect使用
+	styleSystemd            // systemd单元文件的规则，由SystemdDialect使用
+	styleRaw                // 原样读取键值，由DesktopDialect使用
+)
+
+// 预定义的Dialect
+var (
+	// 默认的格式，以`=`作为分隔符，`#`和`;`作为注释。
+	DefaultDialect = &Dialect{
+		Delimiters:      []string{"="},
+		CommentPrefixes: []string{"#", ";"},
+	}
+
+	// Windows的ini文件，仅支持`;`注释，键名和section名称都不区分大小写。
+	WindowsDialect = &Dialect{
+		Delimiters:      []string{"="},
+		CommentPrefixes: []string{";"},
+		LowerKeys:       true,
+		LowerSections:   true,
+	}
+
+	// Python configparser的默认格式，支持`=`和`:`作为分隔符，
+	// 键名不区分大小写，以缩进表示续行。
+	PythonDialect = &Dialect{
+		Delimiters:           []string{"=", ":"},
+		CommentPrefixes:      []string{"#", ";"},
+		LowerKeys:            true,
+		IndentContinuation:   true,
+		SpaceAroundDelimiter: true,
+	}
+
+	// PHP parse_ini_file()的格式，仅支持`;`注释，包括行尾注释。
+	PHPDialect = &Dialect{
+		Delimiters:           []string{"="},
+		CommentPrefixes:      []string{";"},
+		InlineComment:        true,
+		SpaceAroundDelimiter: true,
+	}
+
+	// git config的格式，键名和section名称都不区分大小写，支持行尾注释，
+	// 没有`=`的键名表示其值为true。
+	//
+	// 键值按git的规则处理：引号可以出现在键值的任意位置，仅支持\"、\\、\n、\t和\b转义字符，
+	// 以`\`结尾的行与下一行直接合并，不支持`"""`多行内容和单引号。
+	// 写入时键值对以tab缩进，具体可参考GitConfig。
+	GitDialect = &Dialect{
+		Delimiters:           []string{"="},
+		CommentPrefixes:      []string{"#", ";"},
+		InlineComment:        true,
+		LowerKeys:            true,
+		LowerSections:        true,
+		AllowNoValue:         true,
+		NoValue:              "true",
+		SpaceAroundDelimiter: true,
+		style:                styleGit,
+	}
+
+	// systemd单元文件的格式，键名和section名称都区分大小写，不支持行尾注释。
+	//
+	// 键值原样读取，不处理引号和转义字符，以`\`结尾的行将以空格与下一行合并，
+	// 续行之间的注释行会被忽略。具体可参考SystemdUnit。
+	SystemdDialect = &Dialect{
+		Delimiters:      []string{"="},
+		CommentPrefixes: []string{"#", ";"},
+		style:           styleSystemd,
+	}
+
+	// freedesktop的.desktop文件的格式，键名和section名称都区分大小写，仅支持`#`注释。
+	//
+	// 键值原样读取，不处理引号、转义字符和续行。具体可参考DesktopEntry。
+	DesktopDialect = &Dialect{
+		Delimiters:      []string{"="},
+		CommentPrefixes: []string{"#"},
+		style:           styleRaw,
+	}
+)
+
+// 从可选参数中获取Dialect，并填充其中的默认值。
+func getDialect(d []*Dialect) *Dialect {
+	if len(d) == 0 || d[0] == nil {
+		return DefaultDialect
+	}
+
+	ret := *d[0]
+	if len(ret.Delimiters) == 0 {
+		ret.Delimiters = DefaultDialect.Delimiters
+	}
+	if len(ret.CommentPrefixes) == 0 {
+		ret.CommentPrefixes = DefaultDialect.CommentPrefixes
+	}
+	return &ret
+}
+
+// 若s以注释前缀开头，则返回该前缀，否则返回空字符串。
+func (d *Dialect) commentPrefix(s string) string {
+	for _, p := range d.CommentPrefixes {
+		if strings.HasPrefix(s, p) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// 查找s中最先出现的分隔符，返回其位置及长度，不存在则返回-1和0。
+func (d *Dialect) delimiterIndex(s string) (int, int) {
+	index, size := -1, 0
+	for _, delim := range d.Delimiters {
+		if i := strings.Index(s, delim); i > -1 && (index < 0 || i < index) {
+			index, size = i, len(delim)
+		}
+	}
+
+	return index, size
+}
+
+// 查找行尾注释在val中的起始位置，即第一个以空白字符开头的注释前缀，
+// 若val本身以注释前缀开头，则返回0，不存在则返回-1。
+func (d *Dialect) inlineCommentIndex(val string) int {
+	for i := 0; i < len(val); i++ {
+		if (i == 0 || unicode.IsSpace(rune(val[i-1]))) && len(d.commentPrefix(val[i:])) > 0 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// 转换section名称的大小写，引号中的内容保持不变。
+func (d *Dialect) section(name string) string {
+	if !d.LowerSections {
+		return name
+	}
+
+	if i := strings.IndexAny(name, "\"'"); i > -1 {
+		return strings.ToLower(name[:i]) + name[i:]
+	}
+	return strings.ToLower(name)
+}
+
+// 转换键名的大小写。
+func (d *Dialect) key(name string) string {
+	if !d.LowerKeys {
+		return name
+	}
+
+	return strings.ToLower(name)
+}
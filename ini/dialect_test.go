@@ -0,0 +1,206 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+// 读取r中的所有Token
+func readTokens(a *assert.Assertion, r *Reader) []*Token {
+	tokens := []*Token{}
+	for {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		if token.Type == EOF {
+			return tokens
+		}
+		tokens = append(tokens, &Token{Type: token.Type, Key: token.Key, Value: token.Value, Comment: token.Comment})
+	}
+}
+
+func TestGetDialect(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(getDialect(nil), DefaultDialect)
+	a.Equal(getDialect([]*Dialect{nil}), DefaultDialect)
+
+	d := getDialect([]*Dialect{&Dialect{LowerKeys: true}})
+	a.Equal(d.Delimiters, []string{"="}).
+		Equal(d.CommentPrefixes, []string{"#", ";"}).
+		True(d.LowerKeys)
+}
+
+func TestDialect(t *testing.T) {
+	a := assert.New(t)
+
+	d := &Dialect{Delimiters: []string{"=", ":"}, CommentPrefixes: []string{"//", "#"}}
+	pos, size := d.delimiterIndex("url: http://example.com?a=b")
+	a.Equal(pos, 3).Equal(size, 1)
+	pos, size = d.delimiterIndex("key")
+	a.Equal(pos, -1).Equal(size, 0)
+
+	a.Equal(d.commentPrefix("// comment"), "//").
+		Equal(d.commentPrefix("/ comment"), "")
+
+	a.Equal(d.inlineCommentIndex("http://example.com"), -1).
+		Equal(d.inlineCommentIndex("val // comment"), 4).
+		Equal(d.inlineCommentIndex("// comment"), 0)
+
+	a.Equal(GitDialect.section(`Remote "Origin"`), `remote "Origin"`).
+		Equal(GitDialect.section("Core"), "core").
+		Equal(DefaultDialect.section("Core"), "Core")
+}
+
+func TestReader_Dialect(t *testing.T) {
+	a := assert.New(t)
+
+	// Python configparser
+	r := NewReaderString(`[Section]
+Key: value
+url = http://example.com
+multi = line 1
+    line 2
+	line 3
+
+# comment
+next = 1
+`, PythonDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: "Section"},
+		&Token{Type: Element, Key: "key", Value: "value"},
+		&Token{Type: Element, Key: "url", Value: "http://example.com"},
+		&Token{Type: Element, Key: "multi", Value: "line 1\nline 2\nline 3"},
+		&Token{Type: Comment, Value: " comment"},
+		&Token{Type: Element, Key: "next", Value: "1"},
+	})
+
+	// 续行之后的位置信息
+	r = NewReaderString("k = 1\n  2\nnext = 3", PythonDialect)
+	token, err := r.Token()
+	a.NotError(err).Equal(token.End, Position{Line: 2, Column: 4, Offset: 9})
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Key, "next").Equal(token.Start, Position{Line: 3, Column: 1, Offset: 10})
+
+	// Windows
+	r = NewReaderString("[Section]\nKEY=Value\n; comment\n# not comment", WindowsDialect)
+	r.Tolerant()
+	tokens := []*Token{}
+	for {
+		token, err = r.Token()
+		if token.Type == EOF {
+			break
+		}
+		tokens = append(tokens, token.Copy())
+	}
+	a.Error(err)
+	a.Equal(len(tokens), 3)
+	a.Equal(tokens[0].Value, "section").Equal(tokens[1].Key, "key").Equal(tokens[1].Value, "Value")
+
+	// git
+	r = NewReaderString(`[Core]
+	bare
+	FileMode = false # comment
+[remote "Origin"]
+	url = git@example.com
+`, GitDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: "core"},
+		&Token{Type: Element, Key: "bare", Value: "true"},
+		&Token{Type: Element, Key: "filemode", Value: "false", Comment: " comment"},
+		&Token{Type: Section, Value: `remote "Origin"`},
+		&Token{Type: Element, Key: "url", Value: "git@example.com"},
+	})
+
+	// PHP
+	r = NewReaderString("key = val ; comment\n[s] ; section", PHPDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Element, Key: "key", Value: "val", Comment: " comment"},
+		&Token{Type: Section, Value: "s", Comment: " section"},
+	})
+
+	// 自定义
+	d := &Dialect{
+		Delimiters:      []string{":="},
+		CommentPrefixes: []string{"//"},
+		InlineComment:   true,
+		AllowNoValue:    true,
+		NoValue:         "on",
+	}
+	r = NewReaderString("// comment\nkey := a=b // inline\nflag\n\"quoted flag\" // x\n#key := v", d)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Comment, Value: " comment"},
+		&Token{Type: Element, Key: "key", Value: "a=b", Comment: " inline"},
+		&Token{Type: Element, Key: "flag", Value: "on"},
+		&Token{Type: Element, Key: "quoted flag", Value: "on", Comment: " x"},
+		&Token{Type: Element, Key: "#key", Value: "v"},
+	})
+
+	// 未启用AllowNoValue
+	r = NewReaderString(`"key" x`, GitDialect)
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+}
+
+func TestWriter_Dialect(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0, PythonDialect)
+	a.NotError(err)
+	a.NotError(w.AddComment("comment"))
+	a.NotError(w.AddElement("url", "http://example.com"))
+	a.NotError(w.AddElement("a:b", "v"))
+	a.NotError(w.AddElement("multi", "line 1\nline 2"))
+	a.NotError(w.AddElement("block", "line 1\n  line 2"))
+	w.Flush()
+	a.NotError(w.Err())
+	data := buf.String()
+	a.Equal(data, `#comment
+url = http://example.com
+"a:b" = v
+multi = line 1
+    line 2
+block = """
+line 1
+  line 2
+"""
+`)
+
+	// 可以被读取
+	r := NewReaderString(data, PythonDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Comment, Value: "comment"},
+		&Token{Type: Element, Key: "url", Value: "http://example.com"},
+		&Token{Type: Element, Key: "a:b", Value: "v"},
+		&Token{Type: Element, Key: "multi", Value: "line 1\nline 2"},
+		&Token{Type: Element, Key: "block", Value: "line 1\n  line 2"},
+	})
+
+	// 注释符号必须在Dialect.CommentPrefixes中
+	w, err = NewWriter(buf, '#', WindowsDialect)
+	a.Error(err).Nil(w)
+
+	buf.Reset()
+	d := &Dialect{CommentPrefixes: []string{"//"}}
+	w, err = NewWriter(buf, 0, d)
+	a.NotError(err)
+	a.NotError(w.AddComment("c1\nc2"))
+	a.NotError(w.AddElementComment("k", "v // x", "inline"))
+	a.NotError(w.AddElement("//k", "v"))
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), "//c1\n//c2\nk=\"v // x\" //inline\n\"//k\"=v\n")
+
+	// Encoder
+	buf.Reset()
+	enc := NewEncoder(buf)
+	enc.SetDialect(WindowsDialect)
+	a.NotError(enc.Encode(&struct{ Name string }{Name: "app"}))
+	a.Equal(buf.String(), "Name=app\n")
+}
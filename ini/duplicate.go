@@ -0,0 +1,131 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Duplicate表示重复键名的处理方式。
+type Duplicate int
+
+// 重复键名的处理方式
+const (
+	DuplicateLast  Duplicate = iota // 使用最后一个键值，默认值
+	DuplicateFirst                  // 使用第一个键值
+	DuplicateError                  // 返回错误信息，同时也不允许重复的section
+	DuplicateList                   // 保留所有的键值
+)
+
+// 将键名拆分成名称和是否为数组两部分，以`[]`结尾的键名表示数组：
+//  key[] = a
+//  key[] = b
+func arrayKey(name string) (string, bool) {
+	if len(name) > 2 && strings.HasSuffix(name, "[]") {
+		return name[:len(name)-2], true
+	}
+	return name, false
+}
+
+// 与UnmarshalMap()相同，但可以指定重复键名的处理方式。
+//
+// 同名的section会被合并，键名的重复判断同样跨越这些section；
+// 若d为DuplicateError，则同名的section也会返回错误信息。
+// 以`[]`结尾的键名，将去掉`[]`作为键名，且在DuplicateError模式下允许重复出现。
+// 由于每个键名只能对应一个键值，d不能为DuplicateList，需要保留所有键值的，
+// 可以使用UnmarshalMultiMap()。
+func UnmarshalMapDuplicate(data []byte, d Duplicate) (map[string]map[string]string, error) {
+	if d == DuplicateList {
+		return nil, errors.New("UnmarshalMapDuplicate:不支持DuplicateList，请使用UnmarshalMultiMap()")
+	}
+
+	multi, err := unmarshalMulti(data, d)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]map[string]string, len(multi))
+	for section, items := range multi {
+		m[section] = make(map[string]string, len(items))
+		for key, vals := range items {
+			if d == DuplicateFirst {
+				m[section][key] = vals[0]
+			} else {
+				m[section][key] = vals[len(vals)-1]
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// 将ini转换成map[string]map[string][]string格式的数据，
+// 重复的键名（包括以`[]`结尾的数组形式）会按顺序保留所有的键值，同名的section会被合并：
+//  ExecStart = /bin/a
+//  ExecStart = /bin/b
+//  path[] = /usr
+//  path[] = /opt
+// 对应于：
+//  map[string]map[string][]string{
+//      "": map[string][]string{
+//          "ExecStart": []string{"/bin/a", "/bin/b"},
+//          "path": []string{"/usr", "/opt"},
+//      },
+//  }
+func UnmarshalMultiMap(data []byte) (map[string]map[string][]string, error) {
+	return unmarshalMulti(data, DuplicateList)
+}
+
+// 读取data中的所有键值对，并按d的规则处理重复的键名和section。
+func unmarshalMulti(data []byte, d Duplicate) (map[string]map[string][]string, error) {
+	if len(data) == 0 {
+		return nil, &SyntaxError{Msg: "UnmarshalMap:没有内容", Line: 0}
+	}
+
+	m := map[string]map[string][]string{"": map[string][]string{}}
+	curr := m[""]
+	sectionName := ""
+	scalars := make(map[ref]bool) // 以非数组形式出现过的键名
+
+	r := NewReaderBytes(data)
+	r.Tolerant()
+	for {
+		token, err := r.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Type {
+		case Comment:
+			continue
+		case EOF:
+			return m, nil
+		case Element:
+			key, array := arrayKey(token.Key)
+			k := ref{section: sectionName, key: key}
+			if _, found := curr[key]; found && d == DuplicateError && (!array || scalars[k]) {
+				r.report(r.newSyntaxError(token.Start.Column, "UnmarshalMap", fmt.Errorf("%w%v", ErrDuplicateKey, key)))
+				continue
+			}
+
+			if !array {
+				scalars[k] = true
+			}
+			curr[key] = append(curr[key], token.Value)
+		case Section:
+			sectionName = token.Value
+			if _, found := m[sectionName]; !found {
+				m[sectionName] = map[string][]string{}
+			} else if d == DuplicateError {
+				r.report(r.newSyntaxError(token.Start.Column, "UnmarshalMap", fmt.Errorf("%w%v", ErrDuplicateSection, sectionName)))
+			}
+			curr = m[sectionName]
+		default:
+			return nil, errors.New("UnmarshalMap:未知的元素类型")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+var duplicateTestData = []byte(`key = 1
+key = 2
+[section]
+path[] = /usr
+path[] = /opt
+[other]
+k = v
+[section]
+path[] = /bin
+`)
+
+func TestArrayKey(t *testing.T) {
+	a := assert.New(t)
+
+	name, array := arrayKey("key[]")
+	a.True(array).Equal(name, "key")
+
+	name, array = arrayKey("key")
+	a.False(array).Equal(name, "key")
+
+	name, array = arrayKey("[]")
+	a.False(array).Equal(name, "[]")
+}
+
+func TestUnmarshalMapDuplicate(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := UnmarshalMapDuplicate(duplicateTestData, DuplicateLast)
+	a.NotError(err)
+	a.Equal(m, map[string]map[string]string{
+		"":        map[string]string{"key": "2"},
+		"section": map[string]string{"path": "/bin"},
+		"other":   map[string]string{"k": "v"},
+	})
+
+	m, err = UnmarshalMapDuplicate(duplicateTestData, DuplicateFirst)
+	a.NotError(err)
+	a.Equal(m, map[string]map[string]string{
+		"":        map[string]string{"key": "1"},
+		"section": map[string]string{"path": "/usr"},
+		"other":   map[string]string{"k": "v"},
+	})
+
+	// 重复的键名和section，所有的错误一起返回
+	m, err = UnmarshalMapDuplicate(duplicateTestData, DuplicateError)
+	a.Error(err).Nil(m)
+	errs, ok := err.(SyntaxErrors)
+	a.True(ok).Equal(len(errs), 2)
+	a.Equal(errs[0].Line, 2).True(errors.Is(errs[0], ErrDuplicateKey))
+	a.Equal(errs[1].Line, 8).True(errors.Is(errs[1], ErrDuplicateSection))
+
+	// 数组形式的键名允许重复，但不能与普通的键名混用
+	m, err = UnmarshalMapDuplicate([]byte("k[]=1\nk[]=2"), DuplicateError)
+	a.NotError(err).Equal(m[""]["k"], "2")
+	m, err = UnmarshalMapDuplicate([]byte("k=1\nk[]=2"), DuplicateError)
+	a.Error(err).Nil(m)
+
+	m, err = UnmarshalMapDuplicate(duplicateTestData, DuplicateList)
+	a.Error(err).Nil(m)
+}
+
+func TestUnmarshalMultiMap(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := UnmarshalMultiMap(duplicateTestData)
+	a.NotError(err)
+	a.Equal(m, map[string]map[string][]string{
+		"":        map[string][]string{"key": []string{"1", "2"}},
+		"section": map[string][]string{"path": []string{"/usr", "/opt", "/bin"}},
+		"other":   map[string][]string{"k": []string{"v"}},
+	})
+
+	m, err = UnmarshalMultiMap(nil)
+	a.Error(err).Nil(m)
+}
@@ -0,0 +1,397 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoder将结构体编码成ini格式的数据，并写入到io.Writer中。
+type Encoder struct {
+	w          io.Writer
+	symbol     byte
+	dialect    *Dialect
+	subsection bool
+}
+
+// 声明一个新的Encoder实例，内容将写入到w中。
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// 设置注释符号，只能是当前Dialect.CommentPrefixes中的字符，
+// 比如DefaultDialect的'#'或';'，WindowsDialect则只能是';'。
+// 默认使用Dialect.CommentPrefixes中的第一个。
+//
+// 需要在SetDialect()之后调用，否则Encode()可能因为注释符号与Dialect不匹配而返回错误。
+func (enc *Encoder) SetCommentSymbol(symbol byte) error {
+	prefixes := getDialect([]*Dialect{enc.dialect}).CommentPrefixes
+	for _, p := range prefixes {
+		if p == string(symbol) {
+			enc.symbol = symbol
+			return nil
+		}
+	}
+
+	return fmt.Errorf("SetCommentSymbol:注释符号只能是%v中的一个", strings.Join(prefixes, "、"))
+}
+
+// 设置输出的格式，默认为DefaultDialect。
+func (enc *Encoder) SetDialect(d *Dialect) {
+	enc.dialect = d
+}
+
+// 启用子section，嵌套的结构体字段将作为子section输出，比如[server.http]；
+// map[string]Struct字段中的元素按键名排序之后，以[remote "origin"]的形式输出，
+// 这些元素中不能再包含子section。只包含子section的结构体，不会输出其自身的section名称。
+//
+// 解码时同样需要通过Decoder.AllowSubsection()启用子section。
+// 未启用时，section中不能包含结构体类型的字段，也不能包含map[string]Struct类型的字段。
+func (enc *Encoder) AllowSubsection() {
+	enc.subsection = true
+}
+
+// 将v编码成ini格式的数据并写入到输出流中，v只能是结构体或是指向结构体的指针。
+//
+// 字段与键值对及section的对应关系与Decoder.Decode()相同，
+// 输出时，先输出全局的键值对，之后按字段的顺序依次输出各个section。
+// 值为空指针的字段将被忽略，切片中的每个元素都将作为一个同名的键值对输出。
+// 通过default选项声明了默认值的字段，若为零值(包括空指针)，则输出其默认值。
+// 实现了encoding.TextMarshaler接口或是通过RegisterCodec()注册了Codec的类型，
+// 通过这些方法转换成键值。
+//
+// 字段通过comment选项声明的注释，会输出在对应的键值对或是section之前，
+// 可用于生成带说明的配置文件模板：
+//  Port int `ini:"port;default(8080);comment(监听的端口)"`
+// 将输出：
+//  # 监听的端口
+//  port=8080
+// 注释中不能包含分号和括号，否则返回错误信息，具体可参考tag包。
+//
+// 嵌套的结构体以及map[string]Struct字段需要通过Encoder.AllowSubsection()启用。
+//
+// []Struct字段中的每个元素都作为一个同名的section输出，元素中同样不能再包含子section，
+// 解码时需要通过Decoder.AllowRepeatedSections()启用重复的section。
+func (enc *Encoder) Encode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("Encode:参数v不能为空指针")
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return errors.New("Encode:参数v只能是结构体")
+	}
+	if err := checkFields(rv.Type()); err != nil {
+		return fmt.Errorf("Encode:%v", err)
+	}
+
+	w, err := NewWriter(enc.w, enc.symbol, enc.dialect)
+	if err != nil {
+		return err
+	}
+
+	fields := getFields(rv.Type())
+
+	// 全局的键值对
+	if err = encodeElements(w, rv, fields, ""); err != nil {
+		return err
+	}
+
+	if err = enc.encodeSections(w, rv, fields, nil); err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Err()
+}
+
+// 将v转换成ini格式的数据，具体规则可参考Encoder.Encode()，不会启用子section。
+func Marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// 将结构体v中的section字段依次写入到w中，path为v所在的层级路径。
+func (enc *Encoder) encodeSections(w *Writer, v reflect.Value, fields []*field, path []string) error {
+	for _, f := range fields {
+		if !isSection(f.typ) {
+			continue
+		}
+
+		if !enc.subsection && (len(path) > 0 || isSectionMap(f.typ)) {
+			if len(path) == 0 {
+				return fmt.Errorf("Encode:字段%v需要启用子section", f.name)
+			}
+			return fmt.Errorf("Encode:section[%v]中不能包含结构体类型的字段%v", joinSection(path, false), f.name)
+		}
+
+		p := append(path[:len(path):len(path)], f.name)
+		fv := v.Field(f.index)
+		if isSectionMap(f.typ) {
+			if err := enc.encodeMap(w, fv, p, f.comment); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if isSectionSlice(f.typ) {
+			if err := encodeSlice(w, fv, p, f.comment); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if sv := elemValue(fv); sv.IsValid() {
+			if err := encodeComment(w, f.comment); err != nil {
+				return err
+			}
+			if err := enc.encodeSection(w, sv, p, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 将map[string]Struct类型的v中的元素，按键名排序之后作为子section写入到w中，
+// comment仅输出在第一个元素之前。
+func (enc *Encoder) encodeMap(w *Writer, v reflect.Value, path []string, comment string) error {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	for _, key := range keys {
+		sv := elemValue(v.MapIndex(key))
+		if !sv.IsValid() {
+			continue
+		}
+
+		if err := encodeComment(w, comment); err != nil {
+			return err
+		}
+		comment = ""
+
+		if err := enc.encodeSection(w, sv, append(path[:len(path):len(path)], key.String()), true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 将[]Struct类型的v中的元素依次作为同名的section写入到w中，
+// comment仅输出在第一个元素之前。
+func encodeSlice(w *Writer, v reflect.Value, path []string, comment string) error {
+	name := joinSection(path, false)
+
+	for i := 0; i < v.Len(); i++ {
+		sv := elemValue(v.Index(i))
+		if !sv.IsValid() {
+			continue
+		}
+
+		if err := encodeComment(w, comment); err != nil {
+			return err
+		}
+		comment = ""
+
+		if err := w.AddSection(name); err != nil {
+			return err
+		}
+
+		fields := getFields(sv.Type())
+		if err := encodeElements(w, sv, fields, name); err != nil {
+			return err
+		}
+		if err := noSubsections(fields, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 将结构体v作为路径为path的section写入到w中，其下的section字段作为子section依次写入。
+// quoted表示路径的最后一个元素是否以引号的形式输出。
+func (enc *Encoder) encodeSection(w *Writer, v reflect.Value, path []string, quoted bool) error {
+	name := joinSection(path, quoted)
+	fields := getFields(v.Type())
+
+	// 只包含子section时，不输出空的section名称。
+	if !enc.subsection || !onlySubsections(fields) {
+		if err := w.AddSection(name); err != nil {
+			return err
+		}
+	}
+
+	if err := encodeElements(w, v, fields, name); err != nil {
+		return err
+	}
+
+	if !quoted {
+		return enc.encodeSections(w, v, fields, path)
+	}
+	return noSubsections(fields, name)
+}
+
+// fields中是否只有section字段
+func onlySubsections(fields []*field) bool {
+	for _, f := range fields {
+		if !isSection(f.typ) {
+			return false
+		}
+	}
+	return len(fields) > 0
+}
+
+// 确保名称为name的section中没有子section
+func noSubsections(fields []*field, name string) error {
+	for _, f := range fields {
+		if isSection(f.typ) {
+			return fmt.Errorf("Encode:section[%v]中不能再包含子section%v", name, f.name)
+		}
+	}
+	return nil
+}
+
+// 获取指针v指向的实际对象，若为空指针，则返回零值。
+func elemValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// 将结构体v中所有非section的字段作为键值对写入到w中。
+func encodeElements(w *Writer, v reflect.Value, fields []*field, section string) error {
+	for _, f := range fields {
+		if isSection(f.typ) {
+			continue
+		}
+
+		fv := v.Field(f.index)
+		if f.hasDefault && fv.IsZero() { // 与解码时的规则相同，零值使用默认值
+			if err := encodeDefault(w, f); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr { // 空指针
+			continue
+		}
+
+		if err := encodeComment(w, f.comment); err != nil {
+			return err
+		}
+
+		if isSlice(fv.Type()) { // 切片中的每个元素都作为一个键值对
+			for i := 0; i < fv.Len(); i++ {
+				ev := elemValue(fv.Index(i))
+				if !ev.IsValid() {
+					continue
+				}
+
+				if err := encodeElement(w, ev, f.name, section); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := encodeElement(w, fv, f.name, section); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 将字段f的注释和默认值写入到w中，切片类型的字段，默认值中的每一项都作为一个键值对。
+func encodeDefault(w *Writer, f *field) error {
+	if err := encodeComment(w, f.comment); err != nil {
+		return err
+	}
+
+	vals := f.defaults
+	if !isSlice(f.typ) {
+		vals = []string{strings.Join(f.defaults, ",")}
+	}
+	for _, val := range vals {
+		if err := w.AddElement(f.name, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 输出字段的注释，comment为空时不输出任何内容。
+func encodeComment(w *Writer, comment string) error {
+	if len(comment) == 0 {
+		return nil
+	}
+
+	return w.AddComment(" " + comment)
+}
+
+// 将v作为键名为name的键值写入到w中。
+func encodeElement(w *Writer, v reflect.Value, name, section string) error {
+	val, err := formatValue(v)
+	if err != nil {
+		return fmt.Errorf("Encode:无法转换[%v]中的%v：%v", section, name, err)
+	}
+
+	return w.AddElement(name, val)
+}
+
+// 将v转换成字符串，是setValue()的逆操作。
+func formatValue(v reflect.Value) (string, error) {
+	if val, ok, err := marshalValue(v); ok {
+		return val, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("不支持的类型%v", v.Type())
+	}
+}
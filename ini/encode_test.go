@@ -0,0 +1,310 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	a := assert.New(t)
+
+	ptr := 7
+	conf := &testConfig{
+		Name:   "app",
+		Debug:  true,
+		Count:  -5,
+		Rate:   0.5,
+		Ptr:    &ptr,
+		Ignore: "ignore",
+		Server: testServer{Host: "localhost", Port: 8080, Timeout: 5 * time.Second},
+	}
+
+	data, err := Marshal(conf)
+	a.NotError(err)
+	a.Equal(string(data), `name=app
+debug=true
+count=-5
+rate=0.5
+ptr=7
+[server]
+host=localhost
+port=8080
+timeout=5s
+`)
+
+	// 可以被Unmarshal()还原
+	conf2 := &testConfig{}
+	a.NotError(Unmarshal(data, conf2))
+	a.Equal(conf2, &testConfig{
+		Name:   "app",
+		Debug:  true,
+		Count:  -5,
+		Rate:   0.5,
+		Ptr:    &ptr,
+		Server: testServer{Host: "localhost", Port: 8080, Timeout: 5 * time.Second},
+	})
+
+	// 切片
+	n := 5
+	data, err = Marshal(&struct {
+		Paths []string `ini:"path"`
+		Nums  []*int   `ini:"num"`
+		Empty []int    `ini:"empty"`
+	}{Paths: []string{"/usr", "/opt"}, Nums: []*int{nil, &n}})
+	a.NotError(err)
+	a.Equal(string(data), "path=/usr\npath=/opt\nnum=5\n")
+
+	// 不支持的类型
+	data, err = Marshal(&struct{ Items map[string]int }{})
+	a.Error(err).Nil(data)
+
+	// 未启用子section
+	data, err = Marshal(&testTree{Server: testTreeServer{HTTP: &testServer{}}})
+	a.Error(err).Nil(data)
+	data, err = Marshal(&struct {
+		Remotes map[string]testRemote `ini:"remote"`
+	}{})
+	a.Error(err).Nil(data)
+
+	// 无效的参数
+	data, err = Marshal(5)
+	a.Error(err).Nil(data)
+	data, err = Marshal((*testConfig)(nil))
+	a.Error(err).Nil(data)
+}
+
+// 启用子section之后的Marshal()
+func marshalSubsection(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	enc.AllowSubsection()
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func TestEncoder_AllowSubsection(t *testing.T) {
+	a := assert.New(t)
+
+	conf := &testTree{
+		Server: testTreeServer{
+			Host: "localhost",
+			HTTP: &testServer{Port: 80},
+		},
+		Remotes: map[string]*testRemote{
+			"upstream":   &testRemote{URL: "https://example.com"},
+			"origin":     &testRemote{URL: "git@example.com"},
+			"a.b":        &testRemote{URL: "a.b"},
+			"nil-remote": nil,
+		},
+	}
+
+	data, err := marshalSubsection(conf)
+	a.NotError(err)
+	a.Equal(string(data), `[server]
+host=localhost
+[server.http]
+host=
+port=80
+timeout=0s
+[remote "a.b"]
+url=a.b
+fetch=
+[remote "origin"]
+url=git@example.com
+fetch=
+[remote "upstream"]
+url=https://example.com
+fetch=
+`)
+
+	// 启用子section之后，可以被还原
+	conf2 := &testTree{}
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf2))
+	delete(conf.Remotes, "nil-remote")
+	a.Equal(conf2, conf)
+
+	// 只包含子section的结构体，不输出其自身的section名称
+	type onlySub struct {
+		Server struct {
+			HTTP testServer `ini:"http"`
+		} `ini:"server"`
+	}
+	conf3 := &onlySub{}
+	conf3.Server.HTTP.Port = 8080
+	data, err = marshalSubsection(conf3)
+	a.NotError(err)
+	a.Equal(string(data), "[server.http]\nhost=\nport=8080\ntimeout=0s\n")
+	conf4 := &onlySub{}
+	dec = NewDecoder(bytes.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf4))
+	a.Equal(conf4, conf3)
+
+	// map中的子section不能再包含子section
+	type sub struct{ S struct{} }
+	data, err = marshalSubsection(&struct{ M map[string]sub }{M: map[string]sub{"k": {}}})
+	a.Error(err).Nil(data)
+}
+
+// 写入时总是返回错误的io.Writer
+type errWriter struct{}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("errWriter")
+}
+
+func TestMarshal_Comment(t *testing.T) {
+	a := assert.New(t)
+
+	// 从零值生成带注释的配置文件，零值的字段输出其默认值
+	data, err := marshalSubsection(&testDefaultConfig{})
+	a.NotError(err)
+	a.Equal(string(data), `name=app
+debug=on
+# 服务器设置
+[server]
+# 监听的地址
+host=localhost
+# 监听的端口
+port=8080
+paths=/usr
+paths=/opt
+`)
+
+	conf := &testDefaultConfig{}
+	a.NotError(Unmarshal(data, conf))
+	a.Equal(conf.Name, "app").True(*conf.Debug)
+	a.Equal(conf.Server, testDefaultServer{Host: "localhost", Port: 8080, Paths: []string{"/usr", "/opt"}})
+
+	// 非零值的字段输出实际的值
+	conf.Name = "test"
+	conf.Remotes = map[string]*testDefaultServer{"origin": {Port: 80}, "upstream": {Host: "example.com", Port: 81}}
+
+	data, err = marshalSubsection(conf)
+	a.NotError(err)
+	a.Equal(string(data), `name=test
+debug=true
+# 服务器设置
+[server]
+# 监听的地址
+host=localhost
+# 监听的端口
+port=8080
+paths=/usr
+paths=/opt
+# 远程服务器
+[remote "origin"]
+# 监听的地址
+host=localhost
+# 监听的端口
+port=80
+paths=/usr
+paths=/opt
+[remote "upstream"]
+# 监听的地址
+host=example.com
+# 监听的端口
+port=81
+paths=/usr
+paths=/opt
+`)
+
+	conf2 := &testDefaultConfig{}
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf2))
+	a.Equal(conf2.Server, conf.Server).Equal(conf2.Name, "test")
+
+	// 注释中不能包含括号和分号
+	data, err = Marshal(&struct {
+		Port int `ini:"port;comment(Listening port (TCP))"`
+	}{})
+	a.Error(err).Nil(data)
+	data, err = Marshal(&struct {
+		Server struct {
+			Port int `ini:"port;comment(a;b)"`
+		} `ini:"server"`
+	}{})
+	a.Error(err).Nil(data)
+}
+
+func TestMarshal_Repeated(t *testing.T) {
+	a := assert.New(t)
+
+	conf := &testRepeated{
+		Name:    "app",
+		Servers: []testServer{{Host: "a", Port: 80}, {Host: "b"}},
+		Ptrs:    []*testServer{nil, {Host: "p"}},
+	}
+	conf.Tree.Nodes = []testRemote{{URL: "1"}}
+
+	data, err := marshalSubsection(conf)
+	a.NotError(err)
+	a.Equal(string(data), `name=app
+[server]
+host=a
+port=80
+timeout=0s
+[server]
+host=b
+port=0
+timeout=0s
+[ptr]
+host=p
+port=0
+timeout=0s
+[tree.node]
+url=1
+fetch=
+`)
+
+	conf2 := &testRepeated{}
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.AllowRepeatedSections()
+	dec.AllowSubsection()
+	a.NotError(dec.Decode(conf2))
+	a.Equal(conf2.Servers, conf.Servers).Equal(conf2.Tree, conf.Tree)
+
+	// 元素中不能包含子section
+	_, err = marshalSubsection(&struct {
+		Items []testTreeServer `ini:"item"`
+	}{Items: []testTreeServer{{}}})
+	a.Error(err)
+}
+
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf)
+	a.Error(enc.SetCommentSymbol('/'))
+	a.NotError(enc.SetCommentSymbol(';'))
+	a.NotError(enc.Encode(&testServer{Host: "localhost", Port: 80}))
+	a.Equal(buf.String(), "host=localhost\nport=80\ntimeout=0s\n")
+
+	// 注释符号需要与Dialect相匹配
+	buf.Reset()
+	enc = NewEncoder(buf)
+	enc.SetDialect(WindowsDialect)
+	a.Error(enc.SetCommentSymbol('#'))
+	a.NotError(enc.SetCommentSymbol(';'))
+	a.NotError(enc.Encode(&struct {
+		Port int `ini:"port;comment(端口)"`
+	}{Port: 80}))
+	a.Equal(buf.String(), "; 端口\nport=80\n")
+
+	// 写入错误需要返回
+	a.Error(NewEncoder(&errWriter{}).Encode(&testServer{}))
+}
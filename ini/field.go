@@ -0,0 +1,176 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/issue9/encoding/tag"
+)
+
+// 结构体字段的描述信息。
+type field struct {
+	name     string       // 对应的键名或是section名称
+	index    int          // 在结构体中的索引值
+	typ      reflect.Type // 字段的类型
+	required bool         // 对应的键名或是section是否必须存在
+	comment  string       // 输出时添加在键值对或是section之前的注释
+
+	// 键名不存在时的默认值，切片类型的字段每一项作为一个元素。
+	// 空的默认值，比如default()，与没有默认值相同。
+	defaults   []string
+	hasDefault bool
+
+	err error // 选项中的错误，由checkFields()统一返回
+}
+
+// 获取结构体类型t中所有可导出字段的描述信息。
+//
+// 字段名称默认为字段本身的名称，可以通过struct tag中的ini项进行修改，
+// 若其值为"-"，则忽略该字段。名称之后可以跟以分号分隔的选项，
+// 选项的格式可参考tag包，比如`ini:"port;required;default(8080);comment(监听的端口)"`。
+// 选项中的错误保存在field.err中，可以通过checkFields()获取。
+func getFields(t reflect.Type) []*field {
+	fields := make([]*field, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 { // 不可导出
+			continue
+		}
+
+		name, opts := f.Tag.Get("ini"), ""
+		if j := strings.IndexByte(name, ';'); j > -1 {
+			name, opts = name[:j], name[j+1:]
+		}
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = f.Name
+		}
+
+		defaults, _ := tag.Get(opts, "default")
+		fields = append(fields, &field{
+			name:       name,
+			index:      i,
+			typ:        f.Type,
+			required:   tag.Has(opts, "required"),
+			comment:    strings.Join(tag.MustGet(opts, "comment"), ","),
+			defaults:   defaults,
+			hasDefault: len(defaults) > 0,
+			err:        checkComment(opts),
+		})
+	}
+
+	return fields
+}
+
+// 检测opts中的comment选项，其内容不能包含括号和分号，否则会被tag包错误地解析，
+// 比如comment(端口(TCP))将被解析成`端口 ,TCP`。
+func checkComment(opts string) error {
+	for _, part := range strings.Split(opts, ";") {
+		if !strings.HasPrefix(part, "comment(") {
+			continue
+		}
+
+		c := part[len("comment("):]
+		if !strings.HasSuffix(c, ")") || strings.ContainsAny(c[:len(c)-1], "()") {
+			return errors.New("comment选项中不能包含括号和分号")
+		}
+	}
+
+	return nil
+}
+
+// 检测结构体类型t及其下所有section字段的选项是否正确，返回第一个错误。
+func checkFields(t reflect.Type) error {
+	checked := make(map[reflect.Type]bool)
+
+	var check func(t reflect.Type) error
+	check = func(t reflect.Type) error {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if checked[t] {
+			return nil
+		}
+		checked[t] = true
+
+		for _, f := range getFields(t) {
+			if f.err != nil {
+				return fmt.Errorf("%v.%v：%v", t, f.name, f.err)
+			}
+
+			if isSection(f.typ) {
+				if err := check(f.typ); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	return check(t)
+}
+
+// 从fields中查找名称为name的字段。
+// 优先查找名称完全相同的字段，若不存在且fold为true，则忽略大小写再次查找。
+// sections表示查找的是对应section的字段还是对应键值对的字段。
+func findField(fields []*field, name string, sections, fold bool) *field {
+	var folded *field
+	for _, f := range fields {
+		if isSection(f.typ) != sections {
+			continue
+		}
+
+		if f.name == name {
+			return f
+		}
+
+		if fold && folded == nil && strings.EqualFold(f.name, name) {
+			folded = f
+		}
+	}
+
+	return folded
+}
+
+// 类型t是否对应一个section，即结构体、指向结构体的指针，
+// 以字符串为键名、以这两者为键值的map，或是由这两者组成的切片。
+func isSection(t reflect.Type) bool {
+	return isStruct(t) || isSectionMap(t) || isSectionSlice(t)
+}
+
+// 类型t是否为结构体或是指向结构体的指针，作为键值整体处理的类型除外，具体可参考isValue()。
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !isValue(t)
+}
+
+// 类型t是否为对应一组子section的map，即map[string]Struct或是map[string]*Struct。
+func isSectionMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && isStruct(t.Elem())
+}
+
+// 类型t是否为对应一组重复section的切片，即[]Struct或是[]*Struct。
+func isSectionSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !isValue(t) && isStruct(t.Elem())
+}
+
+// 类型t是否为切片或是指向切片的指针，作为键值整体处理的类型除外，比如net.IP。
+func isSlice(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Slice && !isValue(t)
+}
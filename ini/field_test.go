@@ -0,0 +1,109 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestGetFields(t *testing.T) {
+	a := assert.New(t)
+
+	type section struct {
+		Key string
+	}
+
+	obj := struct {
+		Name     string `ini:"name"`
+		Ignore   string `ini:"-"`
+		unexport string
+		Section  section
+		Ptr      *section `ini:"ptr;required"`
+		Opt      string   `ini:";required"`
+	}{}
+
+	fields := getFields(reflect.TypeOf(obj))
+	a.Equal(len(fields), 4)
+	a.Equal(fields[0].name, "name").Equal(fields[0].index, 0).False(fields[0].required)
+	a.Equal(fields[1].name, "Section").Equal(fields[1].index, 3)
+	a.Equal(fields[2].name, "ptr").Equal(fields[2].index, 4).True(fields[2].required)
+	a.Equal(fields[3].name, "Opt").Equal(fields[3].index, 5).True(fields[3].required)
+
+	// default和comment
+	opts := getFields(reflect.TypeOf(testDefaultServer{}))
+	a.Equal(opts[0].defaults, []string{"localhost"}).True(opts[0].hasDefault)
+	a.Equal(opts[0].comment, "监听的地址")
+	a.Equal(opts[2].defaults, []string{"/usr", "/opt"}).Empty(opts[2].comment)
+	a.False(fields[0].hasDefault)
+
+	opts = getFields(reflect.TypeOf(struct {
+		Name string `ini:"name;default();comment(a,b)"`
+	}{}))
+	a.False(opts[0].hasDefault).Empty(opts[0].defaults).Equal(opts[0].comment, "a,b")
+	a.NotError(opts[0].err)
+
+	// 注释中包含括号或是分号
+	opts = getFields(reflect.TypeOf(struct {
+		A int `ini:"a;comment(Listening port (TCP))"`
+		B int `ini:"b;comment(a;b);default(1)"`
+		C int `ini:"c;comment(a,b);required"`
+	}{}))
+	a.Error(opts[0].err).Error(opts[1].err).NotError(opts[2].err)
+
+	// findField
+	a.Equal(findField(fields, "name", false, true), fields[0])
+	a.Equal(findField(fields, "NAME", false, true), fields[0])
+	a.Nil(findField(fields, "name", true, true))
+	a.Equal(findField(fields, "section", true, true), fields[1])
+	a.Nil(findField(fields, "section", false, true))
+	a.Equal(findField(fields, "ptr", true, true), fields[2])
+	a.Nil(findField(fields, "Ignore", false, true))
+	a.Nil(findField(fields, "unexport", false, true))
+
+	// 不进行大小写无关的匹配
+	a.Equal(findField(fields, "name", false, false), fields[0])
+	a.Nil(findField(fields, "NAME", false, false))
+}
+
+func TestCheckFields(t *testing.T) {
+	a := assert.New(t)
+
+	a.NotError(checkFields(reflect.TypeOf(testDefaultConfig{})))
+	a.NotError(checkFields(reflect.TypeOf(testTree{})))
+
+	type sub struct {
+		Port int `ini:"port;comment(端口(TCP))"`
+	}
+	type node struct {
+		Next *node `ini:"next"` // 循环引用
+		Subs []sub `ini:"sub"`
+	}
+	err := checkFields(reflect.TypeOf(&struct {
+		Nodes map[string]*node `ini:"node"`
+	}{}))
+	a.Error(err).True(strings.Contains(err.Error(), "port"))
+}
+
+func TestIsSection(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(isSection(reflect.TypeOf([]struct{}{})))
+	a.True(isSectionSlice(reflect.TypeOf([]*struct{}{})))
+	a.False(isSectionSlice(reflect.TypeOf([]string{})))
+	a.False(isSectionSlice(reflect.TypeOf(struct{}{})))
+
+	a.True(isSection(reflect.TypeOf(struct{}{})))
+	a.True(isSection(reflect.TypeOf(&struct{}{})))
+	a.False(isSection(reflect.TypeOf(5)))
+	a.False(isSection(reflect.TypeOf("")))
+	a.True(isSection(reflect.TypeOf(map[string]struct{}{})))
+	a.True(isSection(reflect.TypeOf(map[string]*struct{}{})))
+	a.False(isSection(reflect.TypeOf(map[string]string{})))
+	a.False(isSection(reflect.TypeOf(map[int]struct{}{})))
+}
@@ -0,0 +1,431 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+)
+
+// File表示一个完整的ini文档。
+//
+// 与UnmarshalMap()不同，File会保留注释、空行、重复的键名以及各元素的顺序，
+// 修改之后再通过File.Write()输出，未被修改的部分将保持原有的内容不变，
+// 适用于编辑由用户手动维护的配置文件。
+type File struct {
+	Global   *FileSection   // 不属于任何section的键值对，其Name为空。
+	Sections []*FileSection // 按顺序排列的section
+	Comments []string       // 文件末尾的注释
+
+	leading  []string // 文件末尾的注释及空行的原始内容
+	comments []string // 加载时文件末尾的注释，用于判断Comments是否被修改。
+}
+
+// FileSection表示File中的一个section。
+type FileSection struct {
+	Name     string     // section名称
+	Comments []string   // section之前的注释
+	Keys     []*FileKey // 按顺序排列的键值对
+
+	name     string   // 加载时的名称，用于判断Name是否被修改。
+	line     int      // 加载时所在的行号
+	raw      []string // 加载时的原始内容，包含换行符。
+	leading  []string // 之前的注释及空行的原始内容，包含换行符。
+	comments []string // 加载时的注释，用于判断Comments是否被修改。
+}
+
+// FileKey表示File中的一个键值对。
+type FileKey struct {
+	Name          string   // 键名
+	Value         string   // 键值
+	Comments      []string // 键值对之前的注释
+	InlineComment string   // 行尾注释，需要Reader启用了行尾注释才会有内容。
+
+	name     string   // 加载时的键名，用于判断Name是否被修改。
+	value    string   // 加载时的键值，用于判断Value是否被修改。
+	comment  string   // 加载时的行尾注释，用于判断InlineComment是否被修改。
+	line     int      // 加载时所在的行号
+	raw      []string // 加载时的原始内容，包含换行符。
+	leading  []string // 之前的注释及空行的原始内容，包含换行符。
+	comments []string // 加载时的注释，用于判断Comments是否被修改。
+}
+
+// 输出File时使用的Writer
+//
+// 原始内容按加载时的换行符输出，输入源的最后一行可能没有换行符，
+// 若之后还有其它内容，则需要先补上换行符。
+type fileWriter struct {
+	*Writer
+	pending bool // 最后输出的原始内容没有以换行符结尾
+}
+
+// 声明一个空的File实例。
+func NewFile() *File {
+	return &File{
+		Global:   &FileSection{},
+		Sections: []*FileSection{},
+	}
+}
+
+// 从r中加载所有的内容到File实例中。
+//
+// 注释和空行都将归属于其后的section或是键值对，
+// 文件末尾的注释和空行则归属于File本身。
+//
+// 若r启用了容错模式，包含语法错误的行会被当作空行保留，输出时原样写入；
+// 存在语法错误时，将同时返回File实例和SyntaxErrors。
+//
+// 若r启用了包含指令，包含指令本身不会被保留，被包含文件的内容直接合并到File中，
+// 所以File.Write()输出的是合并之后的完整内容，而不是原来的包含指令。
+func LoadFile(r *Reader) (*File, error) {
+	f := NewFile()
+	curr := f.Global
+	var leading, comments []string
+
+	for {
+		token, err := r.Token()
+		if err != nil && (token == nil || token.Type != EOF) {
+			return nil, err
+		}
+
+		leading = append(leading, r.raws[:r.blanks]...)
+		raw := make([]string, len(r.raws)-r.blanks)
+		copy(raw, r.raws[r.blanks:])
+		line := r.current().line - len(raw) + 1
+
+		switch token.Type {
+		case Comment:
+			leading = append(leading, raw...)
+			comments = append(comments, token.Value)
+			continue
+		case Section:
+			curr = &FileSection{
+				Name:     token.Value,
+				Comments: comments,
+				Keys:     []*FileKey{},
+				name:     token.Value,
+				line:     line,
+				raw:      raw,
+				leading:  leading,
+				comments: cloneStrings(comments),
+			}
+			f.Sections = append(f.Sections, curr)
+		case Element:
+			curr.Keys = append(curr.Keys, &FileKey{
+				Name:          token.Key,
+				Value:         token.Value,
+				Comments:      comments,
+				InlineComment: token.Comment,
+				name:          token.Key,
+				value:         token.Value,
+				comment:       token.Comment,
+				line:          line,
+				raw:           raw,
+				leading:       leading,
+				comments:      cloneStrings(comments),
+			})
+		case EOF:
+			f.Comments = comments
+			f.leading = leading
+			f.comments = cloneStrings(comments)
+			return f, err // 容错模式下的SyntaxErrors
+		default:
+			return nil, errors.New("LoadFile:未知的元素类型")
+		}
+
+		leading, comments = nil, nil
+	}
+}
+
+// 查找名称为name的section，若存在多个同名的section，则返回最后一个，
+// 不存在则返回nil。name为空时返回File.Global。
+func (f *File) Section(name string) *FileSection {
+	if len(name) == 0 {
+		return f.Global
+	}
+
+	for i := len(f.Sections) - 1; i >= 0; i-- {
+		if f.Sections[i].Name == name {
+			return f.Sections[i]
+		}
+	}
+
+	return nil
+}
+
+// 添加一个名称为name的section到文件末尾，并返回该section。
+// 若已经存在同名的section，则直接返回已存在的section。
+func (f *File) AddSection(name string) *FileSection {
+	if s := f.Section(name); s != nil {
+		return s
+	}
+
+	s := &FileSection{Name: name, Keys: []*FileKey{}}
+	f.Sections = append(f.Sections, s)
+	return s
+}
+
+// 删除所有名称为name的section，若不存在该section，则返回false。
+func (f *File) DeleteSection(name string) bool {
+	sections := f.Sections[:0]
+	for _, s := range f.Sections {
+		if s.Name != name {
+			sections = append(sections, s)
+		}
+	}
+
+	deleted := len(sections) != len(f.Sections)
+	f.Sections = sections
+	return deleted
+}
+
+// 将所有名称为oldName的section重命名为newName。
+func (f *File) RenameSection(oldName, newName string) error {
+	if len(oldName) == 0 || len(newName) == 0 {
+		return errors.New("RenameSection:section名称不能为空")
+	}
+
+	if f.Section(newName) != nil {
+		return errors.New("RenameSection:已经存在同名的section：" + newName)
+	}
+
+	found := false
+	for _, s := range f.Sections {
+		if s.Name == oldName {
+			s.Name = newName
+			found = true
+		}
+	}
+
+	if !found {
+		return errors.New("RenameSection:不存在的section：" + oldName)
+	}
+	return nil
+}
+
+// 将内容输出到w中，并调用w.Flush()。
+//
+// 未被修改的section、键值对和注释都将按加载时的原始内容输出，
+// 包括其原本的换行符(\n或\r\n)，以及文件末尾是否有换行符；新增或修改的内容则以\n作为换行符。
+func (f *File) Write(writer *Writer) error {
+	w := &fileWriter{Writer: writer}
+
+	if err := f.Global.write(w); err != nil {
+		return err
+	}
+
+	for _, s := range f.Sections {
+		if err := s.write(w); err != nil {
+			return err
+		}
+	}
+
+	if err := writeLeading(w, f.leading, f.comments, f.Comments); err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Err()
+}
+
+// 查找键名为name的键值对，若存在多个同名的键值对，则返回最后一个，
+// 不存在则返回nil。
+func (s *FileSection) Key(name string) *FileKey {
+	for i := len(s.Keys) - 1; i >= 0; i-- {
+		if s.Keys[i].Name == name {
+			return s.Keys[i]
+		}
+	}
+
+	return nil
+}
+
+// 获取键名为name的键值，第二个参数用于判断该键值对是否存在。
+func (s *FileSection) Get(name string) (string, bool) {
+	if k := s.Key(name); k != nil {
+		return k.Value, true
+	}
+
+	return "", false
+}
+
+// 设置键名为name的键值，若不存在，则添加到section的末尾。
+func (s *FileSection) Set(name, value string) *FileKey {
+	if k := s.Key(name); k != nil {
+		k.Value = value
+		return k
+	}
+
+	k := &FileKey{Name: name, Value: value}
+	s.Keys = append(s.Keys, k)
+	return k
+}
+
+// 删除所有键名为name的键值对，若不存在，则返回false。
+func (s *FileSection) Delete(name string) bool {
+	keys := s.Keys[:0]
+	for _, k := range s.Keys {
+		if k.Name != name {
+			keys = append(keys, k)
+		}
+	}
+
+	deleted := len(keys) != len(s.Keys)
+	s.Keys = keys
+	return deleted
+}
+
+// 将所有键名为oldName的键值对重命名为newName。
+func (s *FileSection) RenameKey(oldName, newName string) error {
+	if len(oldName) == 0 || len(newName) == 0 {
+		return errors.New("RenameKey:键名不能为空")
+	}
+
+	if s.Key(newName) != nil {
+		return errors.New("RenameKey:已经存在相同的键名：" + newName)
+	}
+
+	found := false
+	for _, k := range s.Keys {
+		if k.Name == oldName {
+			k.Name = newName
+			found = true
+		}
+	}
+
+	if !found {
+		return errors.New("RenameKey:不存在的键名：" + oldName)
+	}
+	return nil
+}
+
+func (s *FileSection) write(w *fileWriter) error {
+	if len(s.Name) > 0 {
+		if err := writeLeading(w, s.leading, s.comments, s.Comments); err != nil {
+			return err
+		}
+
+		if s.raw != nil && s.Name == s.name {
+			if err := writeRaw(w, s.raw); err != nil {
+				return err
+			}
+		} else {
+			if err := w.endLine(); err != nil {
+				return err
+			}
+			if err := w.AddSection(s.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, k := range s.Keys {
+		if err := k.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (k *FileKey) write(w *fileWriter) error {
+	if err := writeLeading(w, k.leading, k.comments, k.Comments); err != nil {
+		return err
+	}
+
+	if k.raw != nil && k.Name == k.name && k.Value == k.value && k.InlineComment == k.comment {
+		return writeRaw(w, k.raw)
+	}
+
+	if err := w.endLine(); err != nil {
+		return err
+	}
+
+	if len(k.InlineComment) > 0 {
+		return w.AddElementComment(k.Name, k.Value, k.InlineComment)
+	}
+	return w.AddElement(k.Name, k.Value)
+}
+
+// 输出节点之前的注释和空行。
+//
+// 若注释未被修改，则原样输出leading；
+// 否则仅保留第一条注释之前的空行，之后再输出新的注释内容。
+func writeLeading(w *fileWriter, leading, orig, comments []string) error {
+	if equalStrings(orig, comments) {
+		return writeRaw(w, leading)
+	}
+
+	if err := w.endLine(); err != nil {
+		return err
+	}
+
+	for _, line := range leading {
+		if len(strings.TrimSpace(line)) > 0 {
+			break
+		}
+
+		if err := w.NewLine(); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range comments {
+		if err := w.AddComment(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeRaw(w *fileWriter, lines []string) error {
+	for _, line := range lines {
+		if err := w.endLine(); err != nil {
+			return err
+		}
+
+		if err := w.writeRaw(line); err != nil {
+			return err
+		}
+		w.pending = !strings.HasSuffix(line, "\n")
+	}
+
+	return nil
+}
+
+// 若最后输出的原始内容没有换行符，则补上换行符。
+func (w *fileWriter) endLine() error {
+	if !w.pending {
+		return nil
+	}
+
+	w.pending = false
+	return w.NewLine()
+}
+
+func equalStrings(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	ret := make([]string, len(s))
+	copy(ret, s)
+	return ret
+}
@@ -0,0 +1,204 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+var fileTestData = `# global comment
+name = app
+
+  ; section comment
+[ server ]
+host   = localhost
+port=8080
+port=8081
+
+# trailing comment
+
+`
+
+func loadTestFile(a *assert.Assertion) *File {
+	f, err := LoadFile(NewReaderString(fileTestData))
+	a.NotError(err).NotNil(f)
+	return f
+}
+
+func writeTestFile(a *assert.Assertion, f *File) string {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, '#')
+	a.NotError(err)
+	a.NotError(f.Write(w))
+	return buf.String()
+}
+
+func TestLoadFile(t *testing.T) {
+	a := assert.New(t)
+	f := loadTestFile(a)
+
+	a.Equal(len(f.Global.Keys), 1)
+	a.Equal(f.Global.Keys[0].Name, "name").
+		Equal(f.Global.Keys[0].Value, "app").
+		Equal(f.Global.Keys[0].Comments, []string{" global comment"})
+
+	a.Equal(len(f.Sections), 1)
+	s := f.Section("server")
+	a.NotNil(s)
+	a.Equal(s.Comments, []string{" section comment"})
+	a.Equal(len(s.Keys), 3)
+	a.Equal(f.Comments, []string{" trailing comment"})
+
+	// 重复的键名，返回最后一个
+	val, found := s.Get("port")
+	a.True(found).Equal(val, "8081")
+	val, found = s.Get("not-exists")
+	a.False(found).Equal(val, "")
+
+	a.Equal(f.Section(""), f.Global)
+	a.Nil(f.Section("not-exists"))
+
+	// 语法错误
+	f, err := LoadFile(NewReaderString("[server"))
+	a.Error(err).Nil(f)
+}
+
+func TestFile_Write(t *testing.T) {
+	a := assert.New(t)
+
+	// 未作任何修改，输出内容与原内容相同
+	f := loadTestFile(a)
+	a.Equal(writeTestFile(a, f), fileTestData)
+
+	// 修改
+	f = loadTestFile(a)
+	s := f.Section("server")
+	s.Set("port", "9090")
+	s.Set("timeout", "5s").Comments = []string{" new key"}
+	a.True(s.Delete("host"))
+	a.False(s.Delete("host"))
+	f.Global.Keys[0].Comments = nil
+	a.Equal(writeTestFile(a, f), `name = app
+
+  ; section comment
+[ server ]
+port=8080
+port=9090
+# new key
+timeout=5s
+
+# trailing comment
+
+`)
+
+	// 重命名
+	f = loadTestFile(a)
+	a.NotError(f.RenameSection("server", "http"))
+	a.Error(f.RenameSection("not-exists", "new"))
+	a.Error(f.RenameSection("http", "http"))
+	s = f.Section("http")
+	a.NotError(s.RenameKey("host", "addr"))
+	a.Error(s.RenameKey("not-exists", "new"))
+	a.Error(s.RenameKey("addr", "port"))
+	f.Comments = []string{" changed"}
+	a.Equal(writeTestFile(a, f), `# global comment
+name = app
+
+  ; section comment
+[http]
+addr=localhost
+port=8080
+port=8081
+
+# changed
+`)
+
+	// 添加和删除section
+	f = loadTestFile(a)
+	a.Equal(f.AddSection("server"), f.Section("server"))
+	f.AddSection("db").Set("dsn", "root@/db")
+	a.True(f.DeleteSection("server"))
+	a.False(f.DeleteSection("server"))
+	a.Equal(writeTestFile(a, f), `# global comment
+name = app
+[db]
+dsn=root@/db
+
+# trailing comment
+
+`)
+
+	// 新建
+	f = NewFile()
+	f.Global.Set("name", "app")
+	f.AddSection("server").Set("port", "8080")
+	f.Comments = []string{"end"}
+	a.Equal(writeTestFile(a, f), "name=app\n[server]\nport=8080\n#end\n")
+}
+
+func TestFile_Write_LineEnding(t *testing.T) {
+	a := assert.New(t)
+
+	load := func(data string) *File {
+		f, err := LoadFile(NewReaderString(data))
+		a.NotError(err).NotNil(f)
+		return f
+	}
+
+	// 保留原本的换行符
+	data := "# comment\r\nname = app\r\n\r\n[server]\r\nport = 8080\n# end\r\n"
+	a.Equal(writeTestFile(a, load(data)), data)
+
+	// 末尾没有换行符
+	data = "name = app\r\n[server]\r\nport = 8080"
+	a.Equal(writeTestFile(a, load(data)), data)
+	data = "name = app\n# end"
+	a.Equal(writeTestFile(a, load(data)), data)
+
+	// 新增的内容以\n作为换行符，且在没有换行符的最后一行之后
+	f := load("name = app\r\n[server]\r\nport = 8080")
+	f.Section("server").Set("host", "localhost")
+	f.AddSection("db").Set("dsn", "root@/db")
+	a.Equal(writeTestFile(a, f), "name = app\r\n[server]\r\nport = 8080\nhost=localhost\n[db]\ndsn=root@/db\n")
+
+	f = load("name = app\r\n# end")
+	f.Comments = []string{" changed"}
+	a.Equal(writeTestFile(a, f), "name = app\r\n# changed\n")
+}
+
+func TestFile_InlineComment(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewReaderString("port = 8080   ; default\nhost = localhost # host\n")
+	r.AllowInlineComment()
+	f, err := LoadFile(r)
+	a.NotError(err)
+	a.Equal(f.Global.Key("port").InlineComment, " default")
+
+	f.Global.Set("port", "9090")
+	f.Global.Key("host").InlineComment = " changed"
+	a.Equal(writeTestFile(a, f), "port=9090 # default\nhost=localhost # changed\n")
+}
+
+func TestLoadFile_Tolerant(t *testing.T) {
+	a := assert.New(t)
+
+	data := "k1=v1\ninvalid\n[s\n# comment\nk2=v2\n"
+	r := NewReaderString(data)
+	r.Tolerant()
+	f, err := LoadFile(r)
+	a.Error(err).NotNil(f)
+	errs, ok := err.(SyntaxErrors)
+	a.True(ok).Equal(len(errs), 2)
+
+	a.Equal(len(f.Global.Keys), 2)
+	a.Equal(f.Global.Keys[1].Comments, []string{" comment"})
+
+	// 出错的行原样输出
+	a.Equal(writeTestFile(a, f), data)
+}
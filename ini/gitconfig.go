@@ -0,0 +1,461 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// git配置文件中包含文件的最大嵌套层数，与git相同。
+const maxGitIncludeDepth = 10
+
+// GitConfig表示合并了所有配置文件以及被包含文件之后的git配置内容，用于读取配置项。
+//
+// 配置项的名称由section、子section和键名以`.`连接而成，比如remote.origin.url，
+// 其中section和键名不区分大小写，子section区分大小写。
+//
+// 若需要修改配置文件，可以通过GitDialect加载为File，修改之后再输出：
+//  f, err := ini.LoadFile(ini.NewReader(r, ini.GitDialect))
+//  f.AddSection(ini.GitSectionName("remote", "origin")).Set("url", "git@example.com:repo.git")
+//  w, err := ini.NewWriter(out, 0, ini.GitDialect)
+//  err = f.Write(w)
+type GitConfig struct {
+	gitDir  string
+	entries []*gitEntry // 按读取顺序排列的所有配置项
+}
+
+type gitEntry struct {
+	section, subsection, key, value string
+}
+
+// 按顺序加载paths指定的git配置文件，后加载的配置项优先。
+//
+// 配置文件中的[include]和[includeIf]会被展开，被包含文件的内容相当于插入到path所在的位置。
+// 相对路径相对于当前配置文件所在的目录，以`~/`开头的路径则相对于用户的主目录，
+// 不存在的被包含文件将被忽略，与git的行为相同。
+//
+// gitDir为当前仓库的.git目录，用于判断includeIf中的gitdir:、gitdir/i:和onbranch:条件，
+// 为空表示不在任何仓库中，这些条件都不成立。其它的条件都被当作不成立。
+func LoadGitConfig(gitDir string, paths ...string) (*GitConfig, error) {
+	if len(gitDir) > 0 {
+		abs, err := filepath.Abs(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		gitDir = filepath.ToSlash(abs)
+	}
+
+	c := &GitConfig{gitDir: gitDir}
+	for _, p := range paths {
+		if err := c.load(p, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// 加载配置文件p，depth为被包含的层数。
+func (c *GitConfig) load(p string, depth int) error {
+	if depth > maxGitIncludeDepth {
+		return fmt.Errorf("LoadGitConfig:包含%v时超过了最大嵌套层数", p)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if depth > 0 && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	r := NewReader(f, GitDialect)
+	r.current().filename = p
+
+	var section, sub string
+	for {
+		token, err := r.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token.Type {
+		case EOF:
+			return nil
+		case Section:
+			section, sub = splitGitSection(token.Value)
+		case Element:
+			c.entries = append(c.entries, &gitEntry{
+				section:    section,
+				subsection: sub,
+				key:        token.Key,
+				value:      token.Value,
+			})
+
+			if token.Key == "path" && c.match(section, sub, p) {
+				if err = c.load(expandGitPath(token.Value, p), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// 判断section和子section为sub的包含指令是否需要被执行，file为当前配置文件的路径。
+func (c *GitConfig) match(section, sub, file string) bool {
+	switch {
+	case section == "include":
+		return len(sub) == 0
+	case section != "includeif" || len(c.gitDir) == 0:
+		return false
+	}
+
+	var pattern string
+	fold := false
+	switch {
+	case strings.HasPrefix(sub, "gitdir:"):
+		pattern = sub[len("gitdir:"):]
+	case strings.HasPrefix(sub, "gitdir/i:"):
+		pattern, fold = sub[len("gitdir/i:"):], true
+	case strings.HasPrefix(sub, "onbranch:"):
+		data, err := os.ReadFile(filepath.Join(c.gitDir, "HEAD"))
+		if err != nil {
+			return false
+		}
+		branch := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(branch, "ref: refs/heads/") {
+			return false
+		}
+
+		pattern = sub[len("onbranch:"):]
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return matchGlob(pattern, branch[len("ref: refs/heads/"):])
+	default:
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "~/"):
+		pattern = expandGitPath(pattern, file)
+	case strings.HasPrefix(pattern, "./"):
+		pattern = filepath.ToSlash(filepath.Dir(file)) + pattern[1:]
+	case !path.IsAbs(pattern):
+		pattern = "**/" + pattern
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	if fold {
+		return matchGlob(strings.ToLower(pattern), strings.ToLower(c.gitDir))
+	}
+	return matchGlob(pattern, c.gitDir)
+}
+
+// 将包含指令中的路径p转换成实际的路径，file为当前配置文件的路径。
+func expandGitPath(p, file string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.ToSlash(filepath.Join(home, p[2:]))
+		}
+		return p
+	}
+
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(filepath.Dir(file), p)
+}
+
+// 以`/`分隔的通配符匹配，除了path.Match()支持的语法之外，
+// `**`还可以匹配任意层级的目录。
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// 将section名称拆分成section和子section两部分，支持以下两种格式：
+//  [remote "origin"]
+//  [remote.origin]    // 旧的格式
+func splitGitSection(name string) (section, sub string) {
+	if i := strings.IndexByte(name, '"'); i > -1 {
+		if s, _, err := unquote(name[i:]); err == nil {
+			sub = s
+		}
+		return strings.TrimSpace(name[:i]), sub
+	}
+
+	if i := strings.IndexByte(name, '.'); i > -1 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// 生成git格式的section名称，比如GitSectionName("remote", "origin")返回remote "origin"，
+// 可用于在以GitDialect加载的File中查找或是添加section。
+func GitSectionName(section, sub string) string {
+	section = strings.ToLower(section)
+	if len(sub) == 0 {
+		return section
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return section + ` "` + r.Replace(sub) + `"`
+}
+
+// 将配置项的名称拆分成section、子section和键名三部分。
+func splitGitName(name string) (section, sub, key string, err error) {
+	first := strings.IndexByte(name, '.')
+	last := strings.LastIndexByte(name, '.')
+	if first <= 0 || last == len(name)-1 {
+		return "", "", "", fmt.Errorf("无效的配置项名称%v", name)
+	}
+
+	section, key = strings.ToLower(name[:first]), strings.ToLower(name[last+1:])
+	if first < last {
+		sub = name[first+1 : last]
+	}
+	return section, sub, key, nil
+}
+
+// 获取名称为name的配置项的所有值，按读取的顺序排列。
+func (c *GitConfig) GetAll(name string) []string {
+	section, sub, key, err := splitGitName(name)
+	if err != nil {
+		return nil
+	}
+
+	var vals []string
+	for _, e := range c.entries {
+		if e.section == section && e.subsection == sub && e.key == key {
+			vals = append(vals, e.value)
+		}
+	}
+	return vals
+}
+
+// 获取名称为name的配置项的值，若存在多个值，则返回最后一个。
+func (c *GitConfig) Get(name string) (string, bool) {
+	vals := c.GetAll(name)
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[len(vals)-1], true
+}
+
+// 按git的规则获取布尔类型的配置项。
+//
+// true、yes、on、1以及没有`=`的键名表示true，false、no、off、0以及空值表示false，
+// 不区分大小写。配置项不存在或是无法转换时，返回错误信息。
+func (c *GitConfig) Bool(name string) (bool, error) {
+	val, found := c.Get(name)
+	if !found {
+		return false, fmt.Errorf("Bool:配置项%v不存在", name)
+	}
+
+	switch strings.ToLower(val) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("Bool:配置项%v的值%v无法转换成布尔值", name, val)
+}
+
+// 按git的规则获取整数类型的配置项，支持k、m、g等单位后缀，不区分大小写。
+// 配置项不存在或是无法转换时，返回错误信息。
+func (c *GitConfig) Int(name string) (int64, error) {
+	val, found := c.Get(name)
+	if !found {
+		return 0, fmt.Errorf("Int:配置项%v不存在", name)
+	}
+
+	unit := int64(1)
+	if len(val) > 0 {
+		switch val[len(val)-1] {
+		case 'k', 'K':
+			unit = 1 << 10
+		case 'm', 'M':
+			unit = 1 << 20
+		case 'g', 'G':
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			val = val[:len(val)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(val, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Int:配置项%v的值无法转换成整数：%v", name, err)
+	}
+	return n * unit, nil
+}
+
+// 获取section下所有的子section名称，按第一次出现的顺序排列，比如所有remote的名称。
+func (c *GitConfig) Subsections(section string) []string {
+	section = strings.ToLower(section)
+
+	var subs []string
+	found := make(map[string]bool)
+	for _, e := range c.entries {
+		if e.section == section && len(e.subsection) > 0 && !found[e.subsection] {
+			found[e.subsection] = true
+			subs = append(subs, e.subsection)
+		}
+	}
+	return subs
+}
+
+// 按git config的规则解析键值，val为`=`之后的内容：
+// 引号可以出现在任意位置，引号之外的空白字符都被转换成空格，首尾的空白字符被忽略；
+// 支持\"、\\、\n、\t和\b转义字符，以`\`结尾的行将与下一行直接合并；
+// 引号之外的`#`和`;`表示行尾注释的开始。
+func (r *Reader) parseGitValue(val string) (string, error) {
+	val = strings.TrimLeftFunc(val, unicode.IsSpace)
+	buf := make([]byte, 0, len(val))
+	quoted := false
+	space := 0
+
+LINE:
+	for {
+		for i := 0; i < len(val); i++ {
+			c := val[i]
+			if !quoted {
+				if c == ' ' || c == '\t' {
+					if len(buf) > 0 {
+						space++
+					}
+					continue
+				}
+
+				if c == '#' || c == ';' {
+					if r.inline {
+						r.token.Comment = val[i+1:]
+					}
+					break LINE
+				}
+			}
+
+			for ; space > 0; space-- {
+				buf = append(buf, ' ')
+			}
+
+			switch c {
+			case '"':
+				quoted = !quoted
+				continue
+			case '\\':
+				i++
+				if i == len(val) { // 与下一行合并
+					next, ok, err := r.readLine()
+					if err != nil {
+						return "", err
+					}
+					if !ok {
+						break LINE
+					}
+					val = next
+					continue LINE
+				}
+
+				switch val[i] {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				case 'b':
+					c = '\b'
+				case '"', '\\':
+					c = val[i]
+				default:
+					return "", r.newSyntaxError(r.column(val[i-1:]), "parseValue", fmt.Errorf("%w：无效的转义字符\\%c", ErrInvalidQuote, val[i]))
+				}
+			}
+
+			buf = append(buf, c)
+		}
+		break
+	}
+
+	if quoted {
+		return "", r.newSyntaxError(r.column(""), "parseValue", fmt.Errorf("%w：缺少结束的双引号", ErrInvalidQuote))
+	}
+	return string(buf), nil
+}
+
+// git的键名只能由字母、数字和`-`组成，且必须以字母开头。
+func isGitKey(key string) bool {
+	for i, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && (i == 0 || !(c >= '0' && c <= '9' || c == '-')) {
+			return false
+		}
+	}
+
+	return len(key) > 0
+}
+
+// 按git config的规则转换键值，在必要时以双引号包含。
+func gitQuote(val string) string {
+	need := val != strings.TrimSpace(val) || strings.ContainsAny(val, "#;")
+
+	buf := make([]byte, 0, len(val)+2)
+	if need {
+		buf = append(buf, '"')
+	}
+
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	if need {
+		buf = append(buf, '"')
+	}
+	return string(buf)
+}
@@ -0,0 +1,256 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestReader_parseGitValue(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewReaderString(`[alias]
+	lg = log --graph   --oneline
+	tab = a		b
+	q = "a  b"c" d"
+	esc = "\"x\"\t\\y\n"
+	comment = value;comment
+	hash = "#not comment" # comment
+	single = 'x'
+	cont = line1 \
+line2
+	empty =
+`, GitDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: "alias"},
+		&Token{Type: Element, Key: "lg", Value: "log --graph   --oneline"},
+		&Token{Type: Element, Key: "tab", Value: "a  b"},
+		&Token{Type: Element, Key: "q", Value: "a  bc d"},
+		&Token{Type: Element, Key: "esc", Value: "\"x\"\t\\y\n"},
+		&Token{Type: Element, Key: "comment", Value: "value", Comment: "comment"},
+		&Token{Type: Element, Key: "hash", Value: "#not comment", Comment: " comment"},
+		&Token{Type: Element, Key: "single", Value: "'x'"},
+		&Token{Type: Element, Key: "cont", Value: "line1 line2"},
+		&Token{Type: Element, Key: "empty", Value: ""},
+	})
+
+	// 无效的转义字符
+	r = NewReaderString(`key = a\x`, GitDialect)
+	token, err := r.Token()
+	a.Error(err).Nil(token)
+	serr, ok := err.(*SyntaxError)
+	a.True(ok).Equal(serr.Column, 8)
+	a.True(errors.Is(err, ErrInvalidQuote))
+
+	// 缺少结束的引号
+	r = NewReaderString(`key = "abc`, GitDialect)
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+	a.True(errors.Is(err, ErrInvalidQuote))
+}
+
+func TestWriter_Git(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0, GitDialect)
+	a.NotError(err)
+	a.NotError(w.AddSection(GitSectionName("Remote", `my "origin"`)))
+	a.NotError(w.AddElement("url", "git@example.com:repo.git"))
+	a.NotError(w.AddElement("fetch", "+refs/heads/*:refs/remotes/origin/*"))
+	a.NotError(w.AddElement("msg", " a;b\t\"c\"\\\n"))
+	a.NotError(w.AddElementComment("multi", "line1\nline2", "comment"))
+	a.Error(w.AddElement("a_b", "v"))
+	a.Error(w.AddElement("1a", "v"))
+	w.Flush()
+	a.NotError(w.Err())
+	data := buf.String()
+	a.Equal(data, `[remote "my \"origin\""]
+	url = git@example.com:repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	msg = " a;b\t\"c\"\\\n"
+	multi = line1\nline2 #comment
+`)
+
+	// 可以被读取
+	r := NewReaderString(data, GitDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: `remote "my \"origin\""`},
+		&Token{Type: Element, Key: "url", Value: "git@example.com:repo.git"},
+		&Token{Type: Element, Key: "fetch", Value: "+refs/heads/*:refs/remotes/origin/*"},
+		&Token{Type: Element, Key: "msg", Value: " a;b\t\"c\"\\\n"},
+		&Token{Type: Element, Key: "multi", Value: "line1\nline2", Comment: "comment"},
+	})
+}
+
+func TestMatchGlob(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(matchGlob("/home/u/work/**", "/home/u/work/proj/.git"))
+	a.True(matchGlob("**/proj/.git", "/home/u/work/proj/.git"))
+	a.True(matchGlob("/home/*/work/**", "/home/u/work/.git"))
+	a.True(matchGlob("feature/**", "feature/a/b"))
+	a.False(matchGlob("/home/u/work/**", "/home/u/other/.git"))
+	a.False(matchGlob("feature/*", "feature/a/b"))
+}
+
+func TestSplitGitName(t *testing.T) {
+	a := assert.New(t)
+
+	section, sub, key, err := splitGitName("Remote.Origin.URL")
+	a.NotError(err).Equal(section, "remote").Equal(sub, "Origin").Equal(key, "url")
+
+	section, sub, key, err = splitGitName("url.git@example.com:.insteadOf")
+	a.NotError(err).Equal(section, "url").Equal(sub, "git@example.com:").Equal(key, "insteadof")
+
+	section, sub, key, err = splitGitName("core.bare")
+	a.NotError(err).Equal(section, "core").Equal(sub, "").Equal(key, "bare")
+
+	_, _, _, err = splitGitName("core")
+	a.Error(err)
+	_, _, _, err = splitGitName("core.")
+	a.Error(err)
+
+	section, sub = splitGitSection(`remote "Origin"`)
+	a.Equal(section, "remote").Equal(sub, "Origin")
+	section, sub = splitGitSection("branch.main")
+	a.Equal(section, "branch").Equal(sub, "main")
+}
+
+func TestLoadGitConfig(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	gitDir := filepath.Join(dir, "work", "proj", ".git")
+	a.NotError(os.MkdirAll(gitDir, os.ModePerm))
+	a.NotError(os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/feature/x\n"), os.ModePerm))
+
+	write := func(name, data string) string {
+		p := filepath.Join(dir, name)
+		a.NotError(os.WriteFile(p, []byte(data), os.ModePerm))
+		return p
+	}
+
+	write("base.inc", "[user]\n\tname = base\n\temail = base@example.com\n")
+	write("work.inc", "[user]\n\temail = work@example.com\n")
+	write("other.inc", "[user]\n\temail = other@example.com\n")
+	write("branch.inc", "[core]\n\teditor = vim\n")
+	global := write("gitconfig", `[include]
+	path = base.inc
+	path = not-exists.inc
+[includeIf "gitdir:`+filepath.ToSlash(dir)+`/work/"]
+	path = work.inc
+[includeIf "gitdir:other/"]
+	path = other.inc
+[includeIf "onbranch:feature/"]
+	path = branch.inc
+[Core]
+	Bare
+	FileMode = false
+	bigFileThreshold = 2k
+`)
+	local := write("config", `[remote "origin"]
+	url = git@example.com:repo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+[remote "Upstream"]
+	url = git@example.com:upstream.git
+[user]
+	name = local
+`)
+
+	c, err := LoadGitConfig(gitDir, global, local)
+	a.NotError(err).NotNil(c)
+
+	val, found := c.Get("user.name")
+	a.True(found).Equal(val, "local")
+	val, found = c.Get("USER.Email")
+	a.True(found).Equal(val, "work@example.com")
+	val, found = c.Get("core.editor")
+	a.True(found).Equal(val, "vim")
+	_, found = c.Get("user.none")
+	a.False(found)
+
+	a.Equal(c.GetAll("remote.origin.fetch"), []string{
+		"+refs/heads/*:refs/remotes/origin/*",
+		"+refs/tags/*:refs/tags/*",
+	})
+	a.Nil(c.GetAll("remote.Origin.fetch")) // 子section区分大小写
+	a.Equal(c.Subsections("Remote"), []string{"origin", "Upstream"})
+
+	b, err := c.Bool("core.bare")
+	a.NotError(err).True(b)
+	b, err = c.Bool("core.filemode")
+	a.NotError(err).False(b)
+	_, err = c.Bool("core.none")
+	a.Error(err)
+	_, err = c.Bool("user.name")
+	a.Error(err)
+
+	n, err := c.Int("core.bigfilethreshold")
+	a.NotError(err).Equal(n, 2048)
+	_, err = c.Int("user.name")
+	a.Error(err)
+
+	// 不在仓库中，includeIf都不成立。
+	c, err = LoadGitConfig("", global)
+	a.NotError(err).NotNil(c)
+	val, _ = c.Get("user.email")
+	a.Equal(val, "base@example.com")
+	_, found = c.Get("core.editor")
+	a.False(found)
+
+	// 循环包含
+	loop := write("loop", "[include]\n\tpath = loop\n")
+	c, err = LoadGitConfig("", loop)
+	a.Error(err).Nil(c)
+
+	// 语法错误带上文件名
+	bad := write("bad", "[user\n")
+	c, err = LoadGitConfig("", bad)
+	a.Error(err).Nil(c)
+	serr, ok := err.(*SyntaxError)
+	a.True(ok).Equal(serr.Filename, bad)
+
+	// 不存在的文件
+	c, err = LoadGitConfig("", filepath.Join(dir, "not-exists"))
+	a.Error(err).Nil(c)
+}
+
+func TestFile_Git(t *testing.T) {
+	a := assert.New(t)
+
+	f, err := LoadFile(NewReaderString(`# comment
+[core]
+	bare = false ; keep
+[remote "origin"]
+	url = old
+`, GitDialect))
+	a.NotError(err).NotNil(f)
+
+	f.Section(GitSectionName("Remote", "origin")).Set("url", "git@example.com:repo.git")
+	f.AddSection(GitSectionName("branch", "main")).Set("remote", "origin")
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0, GitDialect)
+	a.NotError(err)
+	a.NotError(f.Write(w))
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), `# comment
+[core]
+	bare = false ; keep
+[remote "origin"]
+	url = git@example.com:repo.git
+[branch "main"]
+	remote = origin
+`)
+}
@@ -0,0 +1,218 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 包含指令的默认最大嵌套层数
+const defaultIncludeDepth = 10
+
+// !includedir指令会包含的文件扩展名
+var includeExts = []string{".ini", ".conf", ".cnf"}
+
+// 从fsys中读取名称为name的文件，并启用包含指令。
+//
+// 包含指令中的相对路径，相对于当前文件所在的目录，具体可参考Reader.SetIncludeFS()。
+// 所有打开的文件在读取完毕之后都会被自动关闭，提前结束读取时需要调用Reader.Close()。
+func NewReaderFS(fsys fs.FS, name string, d ...*Dialect) (*Reader, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReader(&source{
+		reader:   bufio.NewReader(f),
+		closer:   f,
+		filename: name,
+	}, getDialect(d))
+	r.fsys = fsys
+	return r, nil
+}
+
+// 启用包含指令，被包含的文件从fsys中查找。支持以下指令：
+//  !include path      // 包含path指定的文件，path可以是通配符
+//  @include path      // 同!include
+//  !includedir dir    // 包含dir目录下所有扩展名为.ini、.conf或.cnf的文件
+// path和dir都是以`/`分隔的路径，相对于当前文件所在的目录，
+// 若当前输入源不是文件或是以`/`开头，则相对于fsys的根目录。
+//
+// 被包含文件的内容相当于直接插入到包含指令所在的位置，
+// 所以其中声明的section会延续到包含指令之后的内容。
+// 通配符匹配的文件和目录下的文件都按文件名排序之后依次读取。
+// 被包含文件中的Token和SyntaxError会带上其所在的文件名。
+// 超过最大嵌套层数或是存在循环包含，都将返回SyntaxError。
+func (r *Reader) SetIncludeFS(fsys fs.FS) {
+	r.fsys = fsys
+}
+
+// 设置包含指令的最大嵌套层数，默认为10。
+func (r *Reader) SetIncludeDepth(depth int) {
+	r.maxDepth = depth
+}
+
+// line是否为包含指令
+func isInclude(line string) bool {
+	name, _ := splitInclude(line)
+	return name == "!include" || name == "!includedir" || name == "@include"
+}
+
+// 将包含指令拆分成指令名称和参数两部分
+func splitInclude(line string) (name, arg string) {
+	if i := strings.IndexFunc(line, unicode.IsSpace); i > 0 {
+		return line[:i], strings.TrimSpace(line[i:])
+	}
+	return line, ""
+}
+
+// 处理包含指令line
+func (r *Reader) include(line string) error {
+	name, arg := splitInclude(line)
+
+	if len(arg) == 0 {
+		return r.newSyntaxError(r.column(line), "include", fmt.Errorf("%w：缺少路径", ErrInclude))
+	}
+
+	curr := r.current()
+	if curr.depth >= r.maxDepth {
+		return r.newSyntaxError(r.column(line), "include", fmt.Errorf("%w：超过了最大嵌套层数", ErrInclude))
+	}
+
+	p := arg
+	if !strings.HasPrefix(p, "/") && len(curr.filename) > 0 {
+		p = path.Join(path.Dir(curr.filename), p)
+	}
+	p = strings.TrimPrefix(path.Clean(p), "/")
+	if len(p) == 0 {
+		p = "."
+	}
+
+	var names []string
+	var err error
+	if name == "!includedir" {
+		names, err = r.includeDir(p)
+	} else {
+		names, err = r.includeFiles(p)
+	}
+	if err != nil {
+		return r.newSyntaxError(r.column(line), "include", fmt.Errorf("%w：%v", ErrInclude, err))
+	}
+
+	for _, n := range names {
+		for s := curr; s != nil; s = s.parent {
+			if s.filename == n {
+				return r.newSyntaxError(r.column(line), "include", fmt.Errorf("%w：循环包含文件%v", ErrInclude, n))
+			}
+		}
+	}
+
+	// 倒序压入，保证按顺序读取。文件在成为当前输入源时才会被打开，具体可参考Reader.open()。
+	serr := r.newSyntaxError(r.column(line), "include", ErrInclude).(*SyntaxError)
+	for i := len(names) - 1; i >= 0; i-- {
+		r.sources = append(r.sources, &source{
+			filename: names[i],
+			depth:    curr.depth + 1,
+			parent:   curr,
+			include:  serr,
+		})
+	}
+
+	return nil
+}
+
+// 打开当前输入源对应的文件，若已经打开，则不作任何操作。
+//
+// 打开失败时，该输入源将被移除，返回的SyntaxError指向对应的包含指令。
+func (r *Reader) open() error {
+	src := r.current()
+	if src.reader != nil {
+		return nil
+	}
+
+	f, err := r.fsys.Open(src.filename)
+	if err != nil {
+		r.sources = r.sources[:len(r.sources)-1]
+
+		serr := *src.include
+		serr.Err = fmt.Errorf("%w：%v", ErrInclude, err)
+		serr.Msg = "include:" + serr.Err.Error()
+		return &serr
+	}
+
+	src.reader = bufio.NewReader(f)
+	src.closer = f
+	return nil
+}
+
+// 关闭所有已经打开的文件，之后的Reader.Token()总是返回EOF。
+//
+// 通过NewReaderFS()以及包含指令打开的文件，在读取完毕，
+// 或是Reader.Token()返回错误之后(容错模式下收集的SyntaxError除外)都会被自动关闭，
+// 若在此之前就不再读取，则需要调用Close()。可以多次调用。
+func (r *Reader) Close() error {
+	var err error
+	for _, src := range r.sources {
+		if src.closer != nil {
+			if e := src.closer.Close(); e != nil && err == nil {
+				err = e
+			}
+			src.closer = nil
+		}
+	}
+
+	root := r.sources[0]
+	root.atEOF = true
+	root.unread = ""
+	r.sources = r.sources[:1]
+	return err
+}
+
+// 获取!include指令对应的文件列表
+func (r *Reader) includeFiles(p string) ([]string, error) {
+	if strings.IndexAny(p, "*?[") < 0 {
+		if _, err := fs.Stat(r.fsys, p); err != nil {
+			return nil, err
+		}
+		return []string{p}, nil
+	}
+
+	names, err := fs.Glob(r.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// 获取!includedir指令对应的文件列表
+func (r *Reader) includeDir(dir string) ([]string, error) {
+	entries, err := fs.ReadDir(r.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		for _, ext := range includeExts {
+			if path.Ext(entry.Name()) == ext {
+				names = append(names, path.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+
+	return names, nil
+}
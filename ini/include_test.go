@@ -0,0 +1,249 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/issue9/assert"
+)
+
+var includeTestFS = fstest.MapFS{
+	"my.cnf": &fstest.MapFile{Data: []byte(`key=root
+!include conf/base.ini
+@include conf/extra/*.ini
+!includedir conf.d
+last=root
+`)},
+	"conf/base.ini":       &fstest.MapFile{Data: []byte("key=base\n")},
+	"conf/extra/b.ini":    &fstest.MapFile{Data: []byte("key=extra-b\n")},
+	"conf/extra/a.ini":    &fstest.MapFile{Data: []byte("key=extra-a\n!include ../base.ini\n")},
+	"conf/extra/c.txt":    &fstest.MapFile{Data: []byte("key=extra-c\n")},
+	"conf.d/2.conf":       &fstest.MapFile{Data: []byte("[section]\nkey=conf.d-2\n")},
+	"conf.d/1.cnf":        &fstest.MapFile{Data: []byte("key=conf.d-1\n")},
+	"conf.d/readme.md":    &fstest.MapFile{Data: []byte("readme")},
+	"cycle/a.ini":         &fstest.MapFile{Data: []byte("key=a\n!include b.ini\n")},
+	"cycle/b.ini":         &fstest.MapFile{Data: []byte("key=b\n!include /cycle/a.ini\n")},
+	"error/main.ini":      &fstest.MapFile{Data: []byte("key=main\n!include error.ini\n")},
+	"error/error.ini":     &fstest.MapFile{Data: []byte("key=error\n[section\n")},
+	"error/not-found.ini": &fstest.MapFile{Data: []byte("!include not-found.ini\n")},
+	"error/empty.ini":     &fstest.MapFile{Data: []byte("!include\n")},
+}
+
+func TestNewReaderFS(t *testing.T) {
+	a := assert.New(t)
+
+	r, err := NewReaderFS(includeTestFS, "my.cnf")
+	a.NotError(err).NotNil(r)
+
+	test := func(typ int, key, val, filename string) {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		a.Equal(token.Type, typ).
+			Equal(token.Key, key).
+			Equal(token.Value, val).
+			Equal(token.Filename, filename)
+	}
+	test(Element, "key", "root", "my.cnf")
+	test(Element, "key", "base", "conf/base.ini")
+	test(Element, "key", "extra-a", "conf/extra/a.ini")
+	test(Element, "key", "base", "conf/base.ini")
+	test(Element, "key", "extra-b", "conf/extra/b.ini")
+	test(Element, "key", "conf.d-1", "conf.d/1.cnf")
+	test(Section, "", "section", "conf.d/2.conf")
+	test(Element, "key", "conf.d-2", "conf.d/2.conf")
+	test(Element, "last", "root", "my.cnf")
+	test(EOF, "", "", "")
+
+	// 不存在的文件
+	r, err = NewReaderFS(includeTestFS, "not-exists.ini")
+	a.Error(err).Nil(r)
+}
+
+func TestReader_Include_Error(t *testing.T) {
+	a := assert.New(t)
+
+	// 读取直到出错为止
+	readAll := func(name string) error {
+		r, err := NewReaderFS(includeTestFS, name)
+		a.NotError(err)
+		for {
+			token, err := r.Token()
+			if err != nil {
+				return err
+			}
+			if token.Type == EOF {
+				return nil
+			}
+		}
+	}
+
+	// 循环包含
+	a.Error(readAll("cycle/a.ini"))
+
+	// 被包含文件中的语法错误
+	err := readAll("error/main.ini")
+	serr, ok := err.(*SyntaxError)
+	a.True(ok).
+		Equal(serr.Filename, "error/error.ini").
+		Equal(serr.Line, 2)
+
+	a.Error(readAll("error/not-found.ini"))
+	a.Error(readAll("error/empty.ini"))
+
+	// 嵌套层数
+	r, err := NewReaderFS(includeTestFS, "my.cnf")
+	a.NotError(err)
+	r.SetIncludeDepth(0)
+	token, err := r.Token()
+	a.NotError(err).Equal(token.Value, "root")
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+}
+
+// 打开指定的文件时出错，并记录打开和关闭文件的次数
+type failFS struct {
+	fstest.MapFS
+	fail   string
+	opened int
+	closed int
+}
+
+type failFile struct {
+	fs.File
+	fsys *failFS
+}
+
+func (fsys *failFS) Open(name string) (fs.File, error) {
+	if name == fsys.fail {
+		return nil, errors.New("failFS:无法打开文件")
+	}
+
+	f, err := fsys.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys.opened++
+	return &failFile{File: f, fsys: fsys}, nil
+}
+
+func (f *failFile) Close() error {
+	f.fsys.closed++
+	return f.File.Close()
+}
+
+func TestReader_Include_Close(t *testing.T) {
+	a := assert.New(t)
+
+	fsys := &failFS{
+		MapFS: fstest.MapFS{
+			"main.ini":   &fstest.MapFile{Data: []byte("!include conf/*.ini\n")},
+			"conf/a.ini": &fstest.MapFile{Data: []byte("key=a\n")},
+			"conf/b.ini": &fstest.MapFile{Data: []byte("key=b\n")},
+			"conf/c.ini": &fstest.MapFile{Data: []byte("key=c\n")},
+		},
+		fail: "conf/c.ini",
+	}
+
+	// 打开conf/c.ini时出错，所有已经打开的文件都被关闭
+	r, err := NewReaderFS(fsys, "main.ini")
+	a.NotError(err).NotNil(r)
+	var serr *SyntaxError
+	for {
+		token, err := r.Token()
+		if err != nil {
+			a.Nil(token).
+				True(errors.As(err, &serr)).
+				True(errors.Is(err, ErrInclude)).
+				Equal(serr.Filename, "main.ini").
+				Equal(serr.Line, 1)
+			break
+		}
+		a.True(token.Type != EOF)
+	}
+	a.Equal(fsys.opened, 3).Equal(fsys.closed, 3)
+	token, err := r.Token()
+	a.NotError(err).Equal(token.Type, EOF)
+
+	// 被包含的文件在读取时才打开，提前结束读取需要调用Close()
+	fsys.opened = 0
+	fsys.closed = 0
+	r, err = NewReaderFS(fsys, "main.ini")
+	a.NotError(err).NotNil(r)
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Value, "a")
+	a.Equal(fsys.opened, 2).Equal(fsys.closed, 0)
+	a.NotError(r.Close()).Equal(fsys.closed, 2)
+	a.NotError(r.Close()).Equal(fsys.closed, 2)
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Type, EOF)
+
+	// 被包含的文件中存在语法错误
+	fsys.fail = ""
+	fsys.opened = 0
+	fsys.closed = 0
+	fsys.MapFS["conf/b.ini"] = &fstest.MapFile{Data: []byte("[b\n")}
+	r, err = NewReaderFS(fsys, "main.ini")
+	a.NotError(err).NotNil(r)
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Value, "a")
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+	a.Equal(fsys.opened, 3).Equal(fsys.closed, 3)
+
+	// 正常读取时，所有的文件都会被关闭
+	fsys.opened = 0
+	fsys.closed = 0
+	fsys.MapFS["conf/b.ini"] = &fstest.MapFile{Data: []byte("key=b\n")}
+	r, err = NewReaderFS(fsys, "main.ini")
+	a.NotError(err).NotNil(r)
+	for {
+		token, err := r.Token()
+		a.NotError(err)
+		if token.Type == EOF {
+			break
+		}
+	}
+	a.Equal(fsys.opened, 4).Equal(fsys.closed, 4)
+}
+
+func TestReader_SetIncludeFS(t *testing.T) {
+	a := assert.New(t)
+
+	// 未设置fs.FS时，包含指令被当作普通的内容
+	r := NewReaderString("!include conf/base.ini\n")
+	token, err := r.Token()
+	a.Error(err).Nil(token)
+
+	r = NewReaderString("!include conf/base.ini\n!important=1\n")
+	r.SetIncludeFS(includeTestFS)
+	token, err = r.Token()
+	a.NotError(err).
+		Equal(token.Value, "base").
+		Equal(token.Filename, "conf/base.ini")
+	token, err = r.Token()
+	a.NotError(err).
+		Equal(token.Key, "!important").
+		Equal(token.Filename, "")
+}
+
+func TestNewDecoderReader(t *testing.T) {
+	a := assert.New(t)
+
+	r, err := NewReaderFS(includeTestFS, "my.cnf")
+	a.NotError(err)
+
+	conf := &struct {
+		Key  string `ini:"key"`
+		Last string `ini:"last"`
+	}{}
+	a.NotError(NewDecoderReader(r).Decode(conf))
+	// 被包含文件中的section会一直延续到被包含文件之后，
+	// 所以last属于[section]，而不是全局的键值对。
+	a.Equal(conf.Key, "conf.d-1").Equal(conf.Last, "")
+}
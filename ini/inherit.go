@@ -0,0 +1,258 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 将[child : parent1, parent2]形式的section名称拆分成section名称和父section列表，
+// 引号中的`:`不作为分隔符，比如[remote "a:b"]。
+func splitInherit(name string) (child string, parents []string) {
+	i := strings.IndexByte(name, ':')
+	if i < 0 {
+		return name, nil
+	}
+	if q := strings.IndexAny(name, "\"'"); q > -1 && q < i {
+		return name, nil
+	}
+
+	for _, p := range strings.Split(name[i+1:], ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			parents = append(parents, p)
+		}
+	}
+	return strings.TrimSpace(name[:i]), parents
+}
+
+// 计算每个section的所有祖先section，按优先级从高到低排列：
+// 直接声明的父section按声明的顺序优先，之后才是父section的祖先。
+// exists用于判断section是否存在，父section不存在或是存在循环继承时返回错误信息。
+func resolveInherit(parents map[string][]string, exists func(string) bool) (map[string][]string, error) {
+	names := make([]string, 0, len(parents))
+	for name := range parents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make(map[string][]string, len(parents))
+	var stack []string
+
+	var resolve func(name string) ([]string, error)
+	resolve = func(name string) ([]string, error) {
+		for i, s := range stack {
+			if s == name {
+				return nil, fmt.Errorf("检测到循环继承：%v", strings.Join(append(append([]string{}, stack[i:]...), name), " -> "))
+			}
+		}
+		if ancestors, found := ret[name]; found {
+			return ancestors, nil
+		}
+
+		stack = append(stack, name)
+		defer func() { stack = stack[:len(stack)-1] }()
+
+		ancestors := []string{}
+		found := map[string]bool{}
+		add := func(names ...string) {
+			for _, n := range names {
+				if !found[n] {
+					found[n] = true
+					ancestors = append(ancestors, n)
+				}
+			}
+		}
+
+		for _, p := range parents[name] {
+			if !exists(p) {
+				return nil, fmt.Errorf("section[%v]的父section[%v]不存在", name, p)
+			}
+		}
+		add(parents[name]...)
+
+		for _, p := range parents[name] {
+			pa, err := resolve(p)
+			if err != nil {
+				return nil, err
+			}
+			add(pa...)
+		}
+
+		ret[name] = ancestors
+		return ancestors, nil
+	}
+
+	for _, name := range names {
+		if _, err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// 处理m中的section继承，m的格式与UnmarshalMap()的返回值相同。
+//
+// section名称可以通过`:`声明一个或多个父section，多个父section以逗号分隔：
+//  [base]
+//  host = localhost
+//  port = 80
+//
+//  [production : base]
+//  host = example.com
+// 处理之后，m中的[production : base]被替换成[production]，
+// 且包含从[base]中继承的port。子section中的键值优先，
+// 多个父section时，靠前的父section优先，所有直接声明的父section都优先于更上层的祖先。
+// 父section不存在或是存在循环继承时，返回错误信息，且不会修改m。
+// 若同时需要变量替换，应该先调用Inherit()，再调用Interpolate()。
+func Inherit(m map[string]map[string]string) error {
+	own := make(map[string]map[string]string, len(m))
+	parents := make(map[string][]string, len(m))
+	for name, items := range m {
+		child, ps := splitInherit(name)
+		if _, found := own[child]; !found {
+			own[child] = make(map[string]string, len(items))
+		}
+		for key, val := range items {
+			own[child][key] = val
+		}
+		parents[child] = append(parents[child], ps...)
+	}
+
+	lineage, err := resolveInherit(parents, func(name string) bool {
+		_, found := own[name]
+		return found
+	})
+	if err != nil {
+		return fmt.Errorf("Inherit:%v", err)
+	}
+
+	for name := range m {
+		delete(m, name)
+	}
+
+	for name, items := range own {
+		ret := make(map[string]string, len(items))
+		for key, val := range items {
+			ret[key] = val
+		}
+
+		for _, a := range lineage[name] {
+			for key, val := range own[a] {
+				if _, found := ret[key]; !found {
+					ret[key] = val
+				}
+			}
+		}
+		m[name] = ret
+	}
+
+	return nil
+}
+
+// 将sections中的[child : parent]形式的section名称替换成child，
+// 并返回每个section声明的父section列表。
+func splitInheritSections(sections []*FileSection) map[string][]string {
+	parents := make(map[string][]string, len(sections))
+	for _, s := range sections {
+		var ps []string
+		s.Name, ps = splitInherit(s.Name)
+		parents[s.Name] = append(parents[s.Name], ps...)
+	}
+
+	return parents
+}
+
+// 将祖先section中的键值对添加到sections中，子section中已经存在的键名不会被添加。
+// sections中重复的section不能声明父section，也不能作为其它section的父section。
+func inheritSections(sections []*FileSection, parents map[string][]string) error {
+	byName := make(map[string]*FileSection, len(sections))
+	repeated := make(map[string]bool)
+	for _, s := range sections {
+		if _, found := byName[s.Name]; found {
+			repeated[s.Name] = true
+		}
+		byName[s.Name] = s
+	}
+
+	if len(repeated) > 0 {
+		if err := checkRepeatedInherit(parents, repeated); err != nil {
+			return err
+		}
+	}
+
+	lineage, err := resolveInherit(parents, func(name string) bool {
+		_, found := byName[name]
+		return found
+	})
+	if err != nil {
+		return err
+	}
+
+	// 先计算所有的结果再写入，保证继承的总是祖先section自身的键值对。
+	keys := make([][]*FileKey, len(sections))
+	for i, s := range sections {
+		keys[i] = s.Keys
+
+		ancestors := lineage[s.Name]
+		if len(ancestors) == 0 {
+			continue
+		}
+
+		own := make(map[string]bool, len(s.Keys))
+		for _, k := range s.Keys {
+			name, _ := arrayKey(k.Name)
+			own[name] = true
+		}
+
+		keys[i] = append(make([]*FileKey, 0, len(s.Keys)), s.Keys...)
+		for _, a := range ancestors {
+			added := make(map[string]bool)
+			for _, k := range byName[a].Keys {
+				name, _ := arrayKey(k.Name)
+				if !own[name] {
+					key := *k // 复制一份，之后的变量替换需要以子section的内容进行。
+					keys[i] = append(keys[i], &key)
+					added[name] = true
+				}
+			}
+
+			for name := range added {
+				own[name] = true
+			}
+		}
+	}
+
+	for i, s := range sections {
+		s.Keys = keys[i]
+	}
+	return nil
+}
+
+// 确保重复的section没有参与继承
+func checkRepeatedInherit(parents map[string][]string, repeated map[string]bool) error {
+	names := make([]string, 0, len(parents))
+	for name := range parents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(parents[name]) == 0 {
+			continue
+		}
+		if repeated[name] {
+			return fmt.Errorf("重复的section[%v]不能声明父section", name)
+		}
+		for _, p := range parents[name] {
+			if repeated[p] {
+				return fmt.Errorf("重复的section[%v]不能作为section[%v]的父section", p, name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestSplitInherit(t *testing.T) {
+	a := assert.New(t)
+
+	child, parents := splitInherit("production : base")
+	a.Equal(child, "production").Equal(parents, []string{"base"})
+
+	child, parents = splitInherit("c:p1, p2,")
+	a.Equal(child, "c").Equal(parents, []string{"p1", "p2"})
+
+	child, parents = splitInherit("base")
+	a.Equal(child, "base").Nil(parents)
+
+	child, parents = splitInherit(`remote "a:b"`)
+	a.Equal(child, `remote "a:b"`).Nil(parents)
+}
+
+func TestResolveInherit(t *testing.T) {
+	a := assert.New(t)
+	exists := func(string) bool { return true }
+
+	lineage, err := resolveInherit(map[string][]string{
+		"a": []string{"b", "c"},
+		"b": []string{"d"},
+		"c": []string{"d"},
+		"d": nil,
+	}, exists)
+	a.NotError(err)
+	a.Equal(lineage["a"], []string{"b", "c", "d"}).
+		Equal(lineage["b"], []string{"d"}).
+		Equal(lineage["d"], []string{})
+
+	_, err = resolveInherit(map[string][]string{
+		"a": []string{"b"},
+		"b": []string{"c"},
+		"c": []string{"a"},
+	}, exists)
+	a.Error(err).True(strings.Contains(err.Error(), "a -> b -> c -> a"))
+
+	_, err = resolveInherit(map[string][]string{"a": []string{"a"}}, exists)
+	a.Error(err)
+
+	_, err = resolveInherit(map[string][]string{"a": []string{"none"}}, func(name string) bool { return name != "none" })
+	a.Error(err).True(strings.Contains(err.Error(), "none"))
+}
+
+func TestInherit(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := UnmarshalMap([]byte(`
+name = app
+[base]
+host = localhost
+port = 80
+debug = true
+
+[log]
+level = info
+debug = false
+
+[staging : base, log]
+host = staging.example.com
+
+[production : staging]
+debug = false
+`))
+	a.NotError(err)
+	a.NotError(Inherit(m))
+	a.Equal(m, map[string]map[string]string{
+		"":     map[string]string{"name": "app"},
+		"base": map[string]string{"host": "localhost", "port": "80", "debug": "true"},
+		"log":  map[string]string{"level": "info", "debug": "false"},
+		"staging": map[string]string{
+			"host":  "staging.example.com",
+			"port":  "80",
+			"debug": "true",
+			"level": "info",
+		},
+		"production": map[string]string{
+			"host":  "staging.example.com",
+			"port":  "80",
+			"debug": "false",
+			"level": "info",
+		},
+	})
+
+	// 直接声明的父section优先于更上层的祖先
+	m = map[string]map[string]string{
+		"d":        map[string]string{"k": "d", "x": "d"},
+		"b : d":    map[string]string{},
+		"c":        map[string]string{"k": "c"},
+		"a : b, c": map[string]string{},
+	}
+	a.NotError(Inherit(m))
+	a.Equal(m["a"], map[string]string{"k": "c", "x": "d"})
+
+	// 循环继承
+	m = map[string]map[string]string{
+		"a : b": map[string]string{"k": "a"},
+		"b : a": map[string]string{"k": "b"},
+	}
+	a.Error(Inherit(m))
+	a.Equal(len(m), 2) // 未被修改
+	_, found := m["a : b"]
+	a.True(found)
+
+	// 父section不存在
+	m = map[string]map[string]string{"a : none": map[string]string{}}
+	a.Error(Inherit(m))
+}
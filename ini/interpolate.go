@@ -0,0 +1,258 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Resolver用于获取${prefix:name}形式的引用所对应的值，
+// 参数name为前缀之后的内容。
+type Resolver func(name string) (string, error)
+
+// Interpolation用于配置键值中的变量替换。支持以下格式的引用：
+//  ${key}           // 当前section中的键值，不存在则查找全局的键值
+//  ${section.key}   // 指定section中的键值
+//  %(key)s          // 同${key}，兼容Python configparser的格式
+//  ${prefix:name}   // 由Resolvers[prefix]解析的值，比如${env:HOME}
+// 若需要输出`$`和`%`本身，可以使用`$$`和`%%`。
+type Interpolation struct {
+	// 是否允许通过${env:NAME}引用环境变量。
+	Env bool
+
+	// 自定义的解析函数，键名为引用的前缀，
+	// 比如Resolvers["vault"]用于解析${vault:db/password}。
+	Resolvers map[string]Resolver
+}
+
+// CycleError表示变量替换时出现了循环引用。
+type CycleError struct {
+	Keys []string // 形成循环的键名，首尾相同
+}
+
+func (err *CycleError) Error() string {
+	return "Interpolate:检测到循环引用：" + strings.Join(err.Keys, " -> ")
+}
+
+// 对m中的所有键值进行变量替换，规则可参考Interpolation。
+// 所有的键值都替换成功之后，才会写入到m中。
+func Interpolate(m map[string]map[string]string, opt *Interpolation) error {
+	i := newInterpolator(opt, func(section, key string) (string, bool) {
+		val, found := m[section][key]
+		return val, found
+	})
+
+	ret := make(map[string]map[string]string, len(m))
+	for section, items := range m {
+		ret[section] = make(map[string]string, len(items))
+		for key := range items {
+			val, err := i.value(section, key)
+			if err != nil {
+				return err
+			}
+			ret[section][key] = val
+		}
+	}
+
+	for section, items := range ret {
+		for key, val := range items {
+			m[section][key] = val
+		}
+	}
+
+	return nil
+}
+
+// 对f中的所有键值进行变量替换。
+// 存在重复的键名时，引用的总是最后一个键值。
+func interpolateFile(f *File, opt *Interpolation) error {
+	i := newInterpolator(opt, func(section, key string) (string, bool) {
+		if s := f.Section(section); s != nil {
+			return s.Get(key)
+		}
+		return "", false
+	})
+
+	sections := append([]*FileSection{f.Global}, f.Sections...)
+	vals := make([][]string, len(sections))
+	for index, s := range sections {
+		vals[index] = make([]string, len(s.Keys))
+		for j, k := range s.Keys {
+			val, err := i.expand(s.Name, k.Name, k.Value)
+			if err != nil {
+				return err
+			}
+			vals[index][j] = val
+		}
+	}
+
+	for index, s := range sections {
+		for j, k := range s.Keys {
+			k.Value = vals[index][j]
+		}
+	}
+
+	return nil
+}
+
+// 变量替换的实现
+type interpolator struct {
+	opt   *Interpolation
+	get   func(section, key string) (string, bool) // 获取未替换的键值
+	cache map[ref]string                           // 已经替换完成的键值
+	stack []ref                                    // 正在替换的键值，用于检测循环引用
+}
+
+// 表示一个被引用的键值
+type ref struct {
+	section, key string
+}
+
+func newInterpolator(opt *Interpolation, get func(section, key string) (string, bool)) *interpolator {
+	if opt == nil {
+		opt = &Interpolation{}
+	}
+
+	return &interpolator{
+		opt:   opt,
+		get:   get,
+		cache: make(map[ref]string),
+		stack: make([]ref, 0, 10),
+	}
+}
+
+// 获取section中键名为key的值，并进行变量替换。
+func (i *interpolator) value(section, key string) (string, error) {
+	r := ref{section: section, key: key}
+	if val, found := i.cache[r]; found {
+		return val, nil
+	}
+
+	for index, item := range i.stack {
+		if item == r {
+			keys := make([]string, 0, len(i.stack)-index+1)
+			for _, item := range i.stack[index:] {
+				keys = append(keys, refName(item.section, item.key))
+			}
+			return "", &CycleError{Keys: append(keys, refName(section, key))}
+		}
+	}
+
+	raw, _ := i.get(section, key)
+	i.stack = append(i.stack, r)
+	val, err := i.expand(section, key, raw)
+	i.stack = i.stack[:len(i.stack)-1]
+	if err != nil {
+		return "", err
+	}
+
+	i.cache[r] = val
+	return val, nil
+}
+
+// 对section中键名为key的值val进行变量替换。
+func (i *interpolator) expand(section, key, val string) (string, error) {
+	if strings.IndexAny(val, "$%") < 0 {
+		return val, nil
+	}
+
+	buf := make([]byte, 0, len(val))
+	for len(val) > 0 {
+		index := strings.IndexAny(val, "$%")
+		if index < 0 || index == len(val)-1 {
+			buf = append(buf, val...)
+			break
+		}
+		buf = append(buf, val[:index]...)
+		val = val[index:]
+
+		var name, rest string
+		switch {
+		case val[0] == val[1]: // $$ 或 %%
+			buf = append(buf, val[0])
+			val = val[2:]
+			continue
+		case val[0] == '$' && val[1] == '{':
+			end := strings.IndexByte(val, '}')
+			if end < 0 {
+				return "", fmt.Errorf("Interpolate:[%v]%v中的${没有对应的}", section, key)
+			}
+			name, rest = val[2:end], val[end+1:]
+		case val[0] == '%' && val[1] == '(':
+			end := strings.Index(val, ")s")
+			if end < 0 {
+				return "", fmt.Errorf("Interpolate:[%v]%v中的%%(没有对应的)s", section, key)
+			}
+			name, rest = val[2:end], val[end+2:]
+		default: // 普通字符
+			buf = append(buf, val[0])
+			val = val[1:]
+			continue
+		}
+
+		v, err := i.resolve(section, name)
+		if err != nil {
+			var cycle *CycleError
+			if errors.As(err, &cycle) {
+				return "", err
+			}
+			return "", fmt.Errorf("Interpolate:[%v]%v中的引用%v：%w", section, key, name, err)
+		}
+		buf = append(buf, v...)
+		val = rest
+	}
+
+	return string(buf), nil
+}
+
+// 获取引用name的值，section为引用所在的section。
+func (i *interpolator) resolve(section, name string) (string, error) {
+	if len(name) == 0 {
+		return "", errors.New("引用不能为空")
+	}
+
+	if index := strings.IndexByte(name, ':'); index > 0 {
+		prefix, name := name[:index], name[index+1:]
+		if r, found := i.opt.Resolvers[prefix]; found {
+			return r(name)
+		}
+
+		if prefix == "env" && i.opt.Env {
+			if val, found := os.LookupEnv(name); found {
+				return val, nil
+			}
+			return "", fmt.Errorf("环境变量%v不存在", name)
+		}
+	}
+
+	if _, found := i.get(section, name); found {
+		return i.value(section, name)
+	}
+
+	if _, found := i.get("", name); found {
+		return i.value("", name)
+	}
+
+	if index := strings.LastIndexByte(name, '.'); index > 0 {
+		s, k := name[:index], name[index+1:]
+		if _, found := i.get(s, k); found {
+			return i.value(s, k)
+		}
+	}
+
+	return "", errors.New("不存在")
+}
+
+// 用于显示的引用名称
+func refName(section, key string) string {
+	if len(section) == 0 {
+		return key
+	}
+
+	return section + "." + key
+}
@@ -0,0 +1,112 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	a := assert.New(t)
+
+	os.Setenv("INI_TEST_HOME", "/home/ini")
+	m := map[string]map[string]string{
+		"": map[string]string{
+			"root": "/opt",
+			"home": "${env:INI_TEST_HOME}",
+		},
+		"app": map[string]string{
+			"dir":    "${root}/app",
+			"bin":    "%(dir)s/bin",
+			"log":    "${log.dir}/app.log",
+			"price":  "$$5 100%%",
+			"secret": "${vault:db}",
+			"plain":  "50% $ 5$",
+		},
+		"log": map[string]string{
+			"dir": "${app.dir}/logs",
+		},
+	}
+
+	opt := &Interpolation{
+		Env: true,
+		Resolvers: map[string]Resolver{
+			"vault": func(name string) (string, error) {
+				return "secret-" + name, nil
+			},
+		},
+	}
+	a.NotError(Interpolate(m, opt))
+	a.Equal(m, map[string]map[string]string{
+		"": map[string]string{
+			"root": "/opt",
+			"home": "/home/ini",
+		},
+		"app": map[string]string{
+			"dir":    "/opt/app",
+			"bin":    "/opt/app/bin",
+			"log":    "/opt/app/logs/app.log",
+			"price":  "$5 100%",
+			"secret": "secret-db",
+			"plain":  "50% $ 5$",
+		},
+		"log": map[string]string{
+			"dir": "/opt/app/logs",
+		},
+	})
+
+	// 未启用环境变量
+	m = map[string]map[string]string{"": map[string]string{"home": "${env:INI_TEST_HOME}"}}
+	a.Error(Interpolate(m, nil))
+	a.Equal(m[""]["home"], "${env:INI_TEST_HOME}") // 出错时不修改内容
+
+	// 不存在的引用
+	m = map[string]map[string]string{"": map[string]string{"key": "${not-exists}"}}
+	a.Error(Interpolate(m, nil))
+
+	// 格式错误
+	m = map[string]map[string]string{"": map[string]string{"key": "${key"}}
+	a.Error(Interpolate(m, nil))
+	m = map[string]map[string]string{"": map[string]string{"key": "%(key"}}
+	a.Error(Interpolate(m, nil))
+	m = map[string]map[string]string{"": map[string]string{"key": "${}"}}
+	a.Error(Interpolate(m, nil))
+
+	// 自定义解析函数返回错误
+	m = map[string]map[string]string{"": map[string]string{"key": "${vault:x}"}}
+	a.Error(Interpolate(m, &Interpolation{Resolvers: map[string]Resolver{
+		"vault": func(string) (string, error) { return "", errors.New("vault") },
+	}}))
+}
+
+func TestInterpolate_Cycle(t *testing.T) {
+	a := assert.New(t)
+
+	m := map[string]map[string]string{
+		"s1": map[string]string{"a": "${s2.b}"},
+		"s2": map[string]string{"b": "%(c)s", "c": "${s1.a}"},
+	}
+	err := Interpolate(m, nil)
+	a.Error(err)
+
+	cycle, ok := err.(*CycleError)
+	a.True(ok)
+	a.Equal(len(cycle.Keys), 4)
+	a.Equal(cycle.Keys[0], cycle.Keys[3])
+	a.True(strings.Contains(err.Error(), "s1.a")).
+		True(strings.Contains(err.Error(), "s2.b")).
+		True(strings.Contains(err.Error(), "s2.c"))
+
+	// 引用自身
+	m = map[string]map[string]string{"": map[string]string{"a": "${a}"}}
+	err = Interpolate(m, nil)
+	cycle, ok = err.(*CycleError)
+	a.True(ok).Equal(cycle.Keys, []string{"a", "a"})
+}
@@ -0,0 +1,151 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Loader用于按顺序合并多个ini数据源，并以环境变量覆盖其中的值。
+//
+//  l := ini.NewLoader()
+//  l.AddFile("base.ini")
+//  l.AddFile("production.ini")
+//  l.SetEnvPrefix("APP")  // APP_SERVER_PORT覆盖[server]中的port
+//  cfg, err := l.Load()
+//  cfg.Section("server").Source("port") // 值的来源，比如production.ini或是env:APP_SERVER_PORT
+type Loader struct {
+	sources []*loaderSource
+	envName func(section, key string) string
+}
+
+type loaderSource struct {
+	name string // 数据源的名称，用于记录值的来源
+	load func() ([]byte, error)
+}
+
+// 声明一个新的Loader实例，默认不从环境变量中读取内容。
+func NewLoader() *Loader {
+	return &Loader{sources: []*loaderSource{}}
+}
+
+// 添加一个文件作为数据源，文件不存在时，Load()将返回错误信息。
+func (l *Loader) AddFile(path string) {
+	l.add(path, func() ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// 添加fsys中的文件作为数据源，文件不存在时，Load()将返回错误信息。
+func (l *Loader) AddFS(fsys fs.FS, name string) {
+	l.add(name, func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+// 添加data作为数据源，name用于记录值的来源。
+func (l *Loader) AddBytes(name string, data []byte) {
+	l.add(name, func() ([]byte, error) {
+		return data, nil
+	})
+}
+
+func (l *Loader) add(name string, load func() ([]byte, error)) {
+	l.sources = append(l.sources, &loaderSource{name: name, load: load})
+}
+
+// 以prefix_SECTION_KEY的格式从环境变量中查找覆盖的值，
+// 非section下的键值对则为prefix_KEY，prefix为空时则省略prefix_部分。
+// 名称都转换成大写，字母和数字之外的字符都替换成`_`，
+// 比如[http.server]中的max-conns对应于APP_HTTP_SERVER_MAX_CONNS。
+func (l *Loader) SetEnvPrefix(prefix string) {
+	l.envName = func(section, key string) string {
+		name := key
+		if len(section) > 0 {
+			name = section + "_" + name
+		}
+		if len(prefix) > 0 {
+			name = prefix + "_" + name
+		}
+		return envName(name)
+	}
+}
+
+// 自定义环境变量的命名规则，fn返回section中的key对应的环境变量名称，
+// 返回空字符串表示该值不能被环境变量覆盖。fn为nil表示不从环境变量中读取内容。
+func (l *Loader) SetEnvName(fn func(section, key string) string) {
+	l.envName = fn
+}
+
+// 将name转换成环境变量的命名格式
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// 按添加的顺序读取所有数据源，并合并成一个Config。
+//
+// 同名的键以最后一个数据源中的值为准，section会被合并，空的数据源将被忽略。
+// 之后再以环境变量覆盖其中的值，环境变量只能覆盖已经存在的键，不会添加新的键。
+// 每个值的来源可以通过ConfigSection.Source()获取。
+func (l *Loader) Load() (*Config, error) {
+	data := map[string]map[string]string{"": map[string]string{}}
+	sources := map[string]map[string]string{"": map[string]string{}}
+
+	for _, src := range l.sources {
+		content, err := src.load()
+		if err != nil {
+			return nil, fmt.Errorf("Load:读取%v时出错：%w", src.name, err)
+		}
+		if len(content) == 0 {
+			continue
+		}
+
+		m, err := UnmarshalMap(content)
+		if err != nil {
+			return nil, fmt.Errorf("Load:解析%v时出错：%w", src.name, err)
+		}
+
+		for section, kv := range m {
+			if _, found := data[section]; !found {
+				data[section] = map[string]string{}
+				sources[section] = map[string]string{}
+			}
+
+			for key, val := range kv {
+				data[section][key] = val
+				sources[section][key] = src.name
+			}
+		}
+	}
+
+	if l.envName != nil {
+		for section, kv := range data {
+			for key := range kv {
+				name := l.envName(section, key)
+				if len(name) == 0 {
+					continue
+				}
+
+				if val, found := os.LookupEnv(name); found {
+					kv[key] = val
+					sources[section][key] = "env:" + name
+				}
+			}
+		}
+	}
+
+	cfg := NewConfig(data)
+	cfg.sources = sources
+	return cfg, nil
+}
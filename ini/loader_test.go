@@ -0,0 +1,110 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/issue9/assert"
+)
+
+func TestLoader(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.ini")
+	a.NotError(os.WriteFile(base, []byte(`debug = false
+[server]
+host = localhost
+port = 8080
+[http.server]
+max-conns = 10
+`), os.ModePerm))
+
+	fsys := fstest.MapFS{
+		"prod.ini":  &fstest.MapFile{Data: []byte("[server]\nhost = example.com\n[db]\ndsn = x\n")},
+		"empty.ini": &fstest.MapFile{Data: []byte{}},
+	}
+
+	t.Setenv("APP_SERVER_PORT", "9090")
+	t.Setenv("APP_HTTP_SERVER_MAX_CONNS", "20")
+	t.Setenv("APP_DEBUG", "true")
+	t.Setenv("APP_SERVER_UNKNOWN", "x")
+
+	l := NewLoader()
+	l.AddFile(base)
+	l.AddFS(fsys, "prod.ini")
+	l.AddFS(fsys, "empty.ini")
+	l.AddBytes("local", []byte("[db]\ndsn = local\n"))
+	l.SetEnvPrefix("APP")
+	cfg, err := l.Load()
+	a.NotError(err).NotNil(cfg)
+
+	server := cfg.Section("server")
+	a.Equal(server.MustString("host", ""), "example.com").
+		Equal(server.Source("host"), "prod.ini")
+	a.Equal(server.MustInt("port", 0), 9090).
+		Equal(server.Source("port"), "env:APP_SERVER_PORT")
+	a.False(server.Has("unknown")).Equal(server.Source("unknown"), "")
+
+	http := cfg.Section("http.server")
+	a.Equal(http.MustInt("max-conns", 0), 20).
+		Equal(http.Source("max-conns"), "env:APP_HTTP_SERVER_MAX_CONNS")
+
+	global := cfg.Section("")
+	a.True(global.MustBool("debug", false)).
+		Equal(global.Source("debug"), "env:APP_DEBUG")
+
+	db := cfg.Section("db")
+	a.Equal(db.MustString("dsn", ""), "local").Equal(db.Source("dsn"), "local")
+
+	// 自定义的命名规则
+	l.SetEnvName(func(section, key string) string {
+		if section == "server" {
+			return "" // 不允许覆盖
+		}
+		return strings.ToUpper(key)
+	})
+	t.Setenv("DSN", "env")
+	cfg, err = l.Load()
+	a.NotError(err).NotNil(cfg)
+	a.Equal(cfg.Section("server").MustInt("port", 0), 8080).
+		Equal(cfg.Section("server").Source("port"), base)
+	a.Equal(cfg.Section("db").MustString("dsn", ""), "env")
+
+	// 不读取环境变量
+	l.SetEnvName(nil)
+	cfg, err = l.Load()
+	a.NotError(err).NotNil(cfg)
+	a.Equal(cfg.Section("db").MustString("dsn", ""), "local")
+
+	// 文件不存在
+	l = NewLoader()
+	l.AddFS(fsys, "not-exists.ini")
+	cfg, err = l.Load()
+	a.Error(err).Nil(cfg)
+	a.True(errors.Is(err, fs.ErrNotExist))
+
+	// 语法错误
+	l = NewLoader()
+	l.AddBytes("bad", []byte("[section"))
+	cfg, err = l.Load()
+	a.Error(err).Nil(cfg)
+	var serrs SyntaxErrors
+	a.True(errors.As(err, &serrs)).True(strings.Contains(err.Error(), "bad"))
+}
+
+func TestEnvName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(envName("app_http.server_max-conns"), "APP_HTTP_SERVER_MAX_CONNS")
+	a.Equal(envName("键"), "_")
+}
@@ -0,0 +1,230 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+)
+
+// OrderedMap按顺序保存section和键值对，可以在读写之间保持原有的顺序。
+//
+//  m, err := ini.UnmarshalOrdered(data)
+//  m.Set("server", "port", "8080") // 已经存在的键名保持原有的位置，否则添加到最后。
+//  data, err = ini.MarshalOrdered(m)
+type OrderedMap struct {
+	sections []*OrderedSection // 第一个元素总是非section下的键值对
+	index    map[string]*OrderedSection
+}
+
+// OrderedSection表示OrderedMap中的一个section。
+type OrderedSection struct {
+	name   string
+	keys   []string
+	values map[string]string
+}
+
+// 声明一个空的OrderedMap实例。
+func NewOrderedMap() *OrderedMap {
+	global := newOrderedSection("")
+	return &OrderedMap{
+		sections: []*OrderedSection{global},
+		index:    map[string]*OrderedSection{"": global},
+	}
+}
+
+func newOrderedSection(name string) *OrderedSection {
+	return &OrderedSection{name: name, keys: []string{}, values: map[string]string{}}
+}
+
+// 将ini转换成OrderedMap，section和键名按第一次出现的顺序排列。
+// 其它规则与UnmarshalMap()相同：重复的键名以最后一个键值为准，同名的section会被合并。
+func UnmarshalOrdered(data []byte) (*OrderedMap, error) {
+	if len(data) == 0 {
+		return nil, &SyntaxError{Msg: "UnmarshalOrdered:没有内容", Line: 0}
+	}
+
+	m := NewOrderedMap()
+	curr := m.sections[0]
+
+	r := NewReaderBytes(data)
+	r.Tolerant()
+	for {
+		token, err := r.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Type {
+		case Comment:
+			continue
+		case EOF:
+			return m, nil
+		case Element:
+			key, _ := arrayKey(token.Key)
+			curr.Set(key, token.Value)
+		case Section:
+			curr = m.AddSection(token.Value)
+		default:
+			return nil, errors.New("UnmarshalOrdered:未知的元素类型")
+		}
+	}
+}
+
+// 获取名称为name的section，name为空表示非section下的键值对，不存在则返回nil。
+func (m *OrderedMap) Section(name string) *OrderedSection {
+	return m.index[name]
+}
+
+// 添加名称为name的section并返回，若已经存在，则直接返回已有的section。
+func (m *OrderedMap) AddSection(name string) *OrderedSection {
+	if s, found := m.index[name]; found {
+		return s
+	}
+
+	s := newOrderedSection(name)
+	m.sections = append(m.sections, s)
+	m.index[name] = s
+	return s
+}
+
+// 获取所有的section，第一个元素总是非section下的键值对。
+func (m *OrderedMap) Sections() []*OrderedSection {
+	return m.sections
+}
+
+// 获取section中名称为key的键值，第二个返回值表示是否存在。
+func (m *OrderedMap) Get(section, key string) (string, bool) {
+	if s := m.Section(section); s != nil {
+		return s.Get(key)
+	}
+	return "", false
+}
+
+// 设置section中名称为key的键值，section不存在时会自动添加。
+func (m *OrderedMap) Set(section, key, val string) {
+	m.AddSection(section).Set(key, val)
+}
+
+// 转换成与UnmarshalMap()返回值相同格式的map。
+func (m *OrderedMap) Map() map[string]map[string]string {
+	ret := make(map[string]map[string]string, len(m.sections))
+	for _, s := range m.sections {
+		items := make(map[string]string, len(s.values))
+		for key, val := range s.values {
+			items[key] = val
+		}
+		ret[s.name] = items
+	}
+
+	return ret
+}
+
+// section的名称，为空表示非section下的键值对。
+func (s *OrderedSection) Name() string {
+	return s.name
+}
+
+// 按顺序获取所有的键名。
+func (s *OrderedSection) Keys() []string {
+	return s.keys
+}
+
+// 获取名称为key的键值，第二个返回值表示是否存在。
+func (s *OrderedSection) Get(key string) (string, bool) {
+	val, found := s.values[key]
+	return val, found
+}
+
+// 设置名称为key的键值，已经存在的键名保持原有的位置，否则添加到最后。
+func (s *OrderedSection) Set(key, val string) {
+	if _, found := s.values[key]; !found {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = val
+}
+
+// 删除名称为key的键值，不存在时不作任何操作。
+func (s *OrderedSection) Delete(key string) {
+	if _, found := s.values[key]; !found {
+		return
+	}
+
+	delete(s.values, key)
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// 将m按顺序转换成ini格式的数据，没有键值对的section也会输出，
+// 但非section下的键值对为空时不会输出任何内容。
+// section名称中包含换行符或是`]`时返回错误信息。
+func MarshalOrdered(m *OrderedMap) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range m.sections {
+		if len(s.name) > 0 {
+			if err = w.AddSection(s.name); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, key := range s.keys {
+			if err = w.AddElement(key, s.values[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	if err = w.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 将UnmarshalMap()格式的m转换成ini格式的数据。
+//
+// 非section下的键值对总是最先输出，之后的section和各section中的键名都按字母顺序排列，
+// 保证相同的m总是得到相同的结果。若需要自定义顺序，可以使用OrderedMap和MarshalOrdered()。
+func MarshalMap(m map[string]map[string]string) ([]byte, error) {
+	return MarshalOrdered(sortedMap(m))
+}
+
+// 将m转换成按字母顺序排列的OrderedMap
+func sortedMap(m map[string]map[string]string) *OrderedMap {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		if len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	ret := NewOrderedMap()
+	for _, name := range append([]string{""}, names...) {
+		items := m[name]
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		s := ret.AddSection(name)
+		for _, key := range keys {
+			s.Set(key, items[key])
+		}
+	}
+
+	return ret
+}
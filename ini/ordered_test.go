@@ -0,0 +1,130 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestUnmarshalOrdered(t *testing.T) {
+	a := assert.New(t)
+
+	data := `name = app
+debug = true
+# comment
+[server]
+port = 80
+host = localhost
+[db]
+user = root
+[server]
+port = 8080
+path[] = /usr
+path[] = /opt
+`
+	m, err := UnmarshalOrdered([]byte(data))
+	a.NotError(err).NotNil(m)
+
+	sections := m.Sections()
+	a.Equal(len(sections), 3)
+	a.Equal(sections[0].Name(), "").Equal(sections[0].Keys(), []string{"name", "debug"})
+	a.Equal(sections[1].Name(), "server").Equal(sections[1].Keys(), []string{"port", "host", "path"})
+	a.Equal(sections[2].Name(), "db").Equal(sections[2].Keys(), []string{"user"})
+
+	val, found := m.Get("server", "port")
+	a.True(found).Equal(val, "8080")
+	val, found = m.Get("server", "path")
+	a.True(found).Equal(val, "/opt")
+	_, found = m.Get("none", "port")
+	a.False(found)
+
+	// 与UnmarshalMap()的结果相同
+	mm, err := UnmarshalMap([]byte(data))
+	a.NotError(err)
+	a.Equal(m.Map(), mm)
+
+	// 读写之间保持顺序
+	m.Set("server", "host", "example.com")
+	m.Set("log", "level", "debug")
+	m.Section("").Delete("debug")
+	m.Section("").Delete("none")
+	out, err := MarshalOrdered(m)
+	a.NotError(err)
+	a.Equal(string(out), `name=app
+[server]
+port=8080
+host=example.com
+path=/opt
+[db]
+user=root
+[log]
+level=debug
+`)
+
+	m, err = UnmarshalOrdered(nil)
+	a.Error(err).Nil(m)
+	m, err = UnmarshalOrdered([]byte("[s"))
+	a.Error(err).Nil(m)
+}
+
+func TestNewOrderedMap(t *testing.T) {
+	a := assert.New(t)
+
+	m := NewOrderedMap()
+	a.NotNil(m.Section("")).Nil(m.Section("s"))
+	s := m.AddSection("s")
+	a.Equal(m.AddSection("s"), s).Equal(m.Section("s"), s)
+
+	// 全局的键值对为空，没有键值对的section依然输出
+	out, err := MarshalOrdered(m)
+	a.NotError(err).Equal(string(out), "[s]\n")
+
+	m.Set("", "k", "v")
+	out, err = MarshalOrdered(m)
+	a.NotError(err).Equal(string(out), "k=v\n[s]\n")
+}
+
+func TestMarshalMap(t *testing.T) {
+	a := assert.New(t)
+
+	m := map[string]map[string]string{
+		"server": {"port": "8080", "host": "localhost"},
+		"":       {"name": "app", "debug": "true"},
+		"db":     {"user": "root"},
+	}
+	data, err := MarshalMap(m)
+	a.NotError(err)
+	a.Equal(string(data), `debug=true
+name=app
+[db]
+user=root
+[server]
+host=localhost
+port=8080
+`)
+
+	// 可以被UnmarshalMap()还原
+	m2, err := UnmarshalMap(data)
+	a.NotError(err).Equal(m2, m)
+
+	// 没有全局的键值对
+	data, err = MarshalMap(map[string]map[string]string{"s": {"k": "v"}})
+	a.NotError(err).Equal(string(data), "[s]\nk=v\n")
+
+	data, err = MarshalMap(nil)
+	a.NotError(err).Empty(data)
+
+	// 无效的section名称
+	data, err = MarshalMap(map[string]map[string]string{"a]b": {"k": "v"}})
+	a.Error(err).Nil(data)
+	data, err = MarshalMap(map[string]map[string]string{"a\nb": {"k": "v"}})
+	a.Error(err).Nil(data)
+	m3 := NewOrderedMap()
+	m3.Set("a]b", "k", "v")
+	data, err = MarshalOrdered(m3)
+	a.Error(err).Nil(data)
+}
@@ -0,0 +1,128 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 解析以引号开头的字符串s，返回引号中的内容，以及结束引号之后的内容。
+//
+// 双引号中的内容支持以下转义字符：\n,\t,\r,\",\',\\以及\uXXXX；
+// 单引号中的内容则原样返回，不支持任何转义字符。
+func unquote(s string) (val, rest string, err error) {
+	if s[0] == '\'' {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", errors.New("缺少结束的单引号")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	buf := make([]byte, 0, len(s))
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return string(buf), s[i+1:], nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", "", errors.New("缺少结束的双引号")
+			}
+
+			switch s[i] {
+			case 'n':
+				buf = append(buf, '\n')
+			case 't':
+				buf = append(buf, '\t')
+			case 'r':
+				buf = append(buf, '\r')
+			case '"', '\'', '\\':
+				buf = append(buf, s[i])
+			case 'u':
+				if i+5 > len(s) {
+					return "", "", errors.New("无效的转义字符\\u")
+				}
+				n, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("无效的转义字符\\u%v", s[i+1:i+5])
+				}
+				buf = append(buf, string(rune(n))...)
+				i += 4
+			default:
+				return "", "", fmt.Errorf("无效的转义字符\\%c", s[i])
+			}
+		default:
+			buf = append(buf, s[i])
+		}
+	}
+
+	return "", "", errors.New("缺少结束的双引号")
+}
+
+// 将s转换成以双引号包含的字符串，是unquote()的逆操作。
+func quote(s string) string {
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf = append(buf, '\\', byte(r))
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		default:
+			if unicode.IsControl(r) {
+				buf = append(buf, fmt.Sprintf("\\u%04x", r)...)
+			} else {
+				buf = append(buf, string(r)...)
+			}
+		}
+	}
+
+	return string(append(buf, '"'))
+}
+
+// 键名是否需要以引号的形式输出。
+func keyNeedQuote(key string, d *Dialect) bool {
+	switch key[0] {
+	case '"', '\'', '[':
+		return true
+	}
+
+	pos, _ := d.delimiterIndex(key)
+	return key != strings.TrimSpace(key) ||
+		pos > -1 ||
+		len(d.commentPrefix(key)) > 0 ||
+		strings.IndexFunc(key, unicode.IsControl) > -1
+}
+
+// 键值是否需要以引号的形式输出。
+//
+// 可能被当作行尾注释的内容也会被引号包含，
+// 保证无论Reader是否启用了行尾注释，都能正确读取。
+func valueNeedQuote(val string, d *Dialect) bool {
+	if len(val) == 0 {
+		return false
+	}
+
+	switch val[0] {
+	case '"', '\'':
+		return true
+	}
+
+	return val != strings.TrimSpace(val) ||
+		strings.HasSuffix(val, "\\") ||
+		strings.IndexAny(val, "\r\n") > -1 ||
+		d.inlineCommentIndex(val) > -1
+}
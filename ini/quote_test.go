@@ -0,0 +1,64 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestUnquote(t *testing.T) {
+	a := assert.New(t)
+
+	val, rest, err := unquote(`"a\n\t\r\"\'\\中b" rest`)
+	a.NotError(err).
+		Equal(val, "a\n\t\r\"'\\中b").
+		Equal(rest, " rest")
+
+	val, rest, err = unquote(`'a\nb'rest`)
+	a.NotError(err).
+		Equal(val, `a\nb`).
+		Equal(rest, "rest")
+
+	val, rest, err = unquote(`""`)
+	a.NotError(err).Equal(val, "").Equal(rest, "")
+
+	// 各类错误
+	for _, s := range []string{`"abc`, `'abc`, `"abc\`, `"\x"`, `"\u12"`, `"\u12zz"`} {
+		_, _, err = unquote(s)
+		a.Error(err, "未对%v返回错误信息", s)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	a := assert.New(t)
+
+	for _, s := range []string{"", "abc", " a b ", "a\n\t\r\"'\\b", "中\x00\x7f"} {
+		q := quote(s)
+		val, rest, err := unquote(q)
+		a.NotError(err).Equal(val, s).Equal(rest, "")
+	}
+
+	a.Equal(quote("a\"b\n\x01"), `"a\"b\n\u0001"`)
+}
+
+func TestNeedQuote(t *testing.T) {
+	a := assert.New(t)
+
+	for _, key := range []string{`"key`, "'key", "[key", "#key", ";key", " key", "k=ey", "k\ney"} {
+		a.True(keyNeedQuote(key, DefaultDialect), "%v需要引号", key)
+	}
+	for _, key := range []string{"key", "k ey", "ke#y", "k\"ey"} {
+		a.False(keyNeedQuote(key, DefaultDialect), "%v不需要引号", key)
+	}
+
+	for _, val := range []string{`"val`, "'val", " val", "val ", `val\`, "v\nal", "v\ral", "#val", ";val", "v #al", "v\t;al"} {
+		a.True(valueNeedQuote(val, DefaultDialect), "%v需要引号", val)
+	}
+	for _, val := range []string{"", "val", "v al", "v#al", "v;al", "v\"al", "=val"} {
+		a.False(valueNeedQuote(val, DefaultDialect), "%v不需要引号", val)
+	}
+}
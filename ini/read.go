@@ -10,20 +10,123 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"strings"
 	"unicode"
 )
 
+// 语法错误的具体类型，可以通过errors.Is()判断SyntaxError的类型：
+//  if errors.Is(err, ini.ErrMissingDelimiter) {
+//      // ...
+//  }
+var (
+	ErrUnterminatedSection = errors.New("section名称没有以]作为结尾")
+	ErrEmptySectionName    = errors.New("section名称不能为空字符串")
+	ErrInvalidSectionName  = errors.New("无效的section名称")
+	ErrMissingDelimiter    = errors.New("表达式中未找到`=`符号")
+	ErrEmptyKey            = errors.New("键名不能为空")
+	ErrInvalidKey          = errors.New("无效的键名")
+	ErrInvalidQuote        = errors.New("无效的引号内容")
+	ErrUnterminatedBlock   = errors.New("多行内容没有以`\"\"\"`作为结尾")
+	ErrInclude             = errors.New("无效的包含指令")
+	ErrDuplicateKey        = errors.New("重复的键名")
+	ErrDuplicateSection    = errors.New("重复的section")
+)
+
 // 表示ini的语法错误信息。
 type SyntaxError struct {
-	Line int
-	Msg  string
+	Filename string // 发生错误的文件名，非文件的输入源为空
+	Line     int    // 发生错误的行号，从1开始，0表示与具体的行无关。
+	Column   int    // 发生错误的列号，以字节为单位，从1开始，0表示未知。
+	Source   string // 发生错误的行的原始内容
+	Msg      string
+	Err      error // 错误的具体类型，比如ErrMissingDelimiter，可能为nil。
 }
 
+// Line为0表示错误与具体的行无关，比如缺少必需的section，此时不输出位置信息。
 func (s *SyntaxError) Error() string {
-	return fmt.Sprintf("encoding/ini，在第%d行发生语法错误：%v", s.Line, s.Msg)
+	if s.Line == 0 {
+		if len(s.Filename) > 0 {
+			return fmt.Sprintf("encoding/ini，在%v中发生错误：%v", s.Filename, s.Msg)
+		}
+		return "encoding/ini，" + s.Msg
+	}
+
+	pos := fmt.Sprintf("第%d行", s.Line)
+	if s.Column > 0 {
+		pos += fmt.Sprintf("第%d列", s.Column)
+	}
+
+	if len(s.Filename) > 0 {
+		return fmt.Sprintf("encoding/ini，在%v的%v发生语法错误：%v", s.Filename, pos, s.Msg)
+	}
+	return fmt.Sprintf("encoding/ini，在%v发生语法错误：%v", pos, s.Msg)
+}
+
+func (s *SyntaxError) Unwrap() error {
+	return s.Err
 }
 
+// SyntaxErrors表示容错模式下收集到的所有语法错误，按出现的顺序排列。
+//
+// 实现了Unwrap() []error，可以通过errors.Is()和errors.As()判断其中的错误。
+type SyntaxErrors []*SyntaxError
+
+func (errs SyntaxErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs SyntaxErrors) Unwrap() []error {
+	ret := make([]error, 0, len(errs))
+	for _, err := range errs {
+		ret = append(ret, err)
+	}
+	return ret
+}
+
+// 返回发生错误的行内容，以及在其下一行中指向错误位置的`^`符号：
+//  [server
+//         ^
+// 若Column为0，则只返回行内容。
+func (s *SyntaxError) Excerpt() string {
+	if s.Column <= 0 {
+		return s.Source
+	}
+
+	prefix := s.Source
+	if len(prefix) > s.Column-1 {
+		prefix = prefix[:s.Column-1]
+	}
+
+	// 保留制表符，其它字符都以一个空格代替，以保证对齐。
+	caret := make([]rune, 0, len(prefix)+1)
+	for _, r := range prefix {
+		if r != '\t' {
+			r = ' '
+		}
+		caret = append(caret, r)
+	}
+	for i := len(prefix); i < s.Column-1; i++ {
+		caret = append(caret, ' ')
+	}
+
+	return s.Source + "\n" + string(append(caret, '^'))
+}
+
+// Position表示在输入源中的位置。
+type Position struct {
+	Line   int // 行号，从1开始
+	Column int // 列号，以字节为单位，从1开始
+	Offset int // 相对于输入源起始位置的字节偏移量，从0开始
+}
+
+// 多行内容的分隔符
+const blockDelim = `"""`
+
 // ini节点元素类型
 const (
 	Undefined = iota // 未定义，初始状态
@@ -35,136 +138,626 @@ const (
 
 // Token用于描述每一个节点的类型信息及数据内容。
 type Token struct {
-	Type  int    // 类型，可以是上面的任意节点类型
-	Key   string // 该节点的键名，仅在Type值为Element时才有效
-	Value string // 该节点对应的值
+	Type     int    // 类型，可以是上面的任意节点类型
+	Key      string // 该节点的键名，仅在Type值为Element时才有效
+	Value    string // 该节点对应的值
+	Comment  string // 行尾注释，仅在启用行尾注释且Type值为Element或Section时才有效
+	Filename string // 该节点所在的文件名，非文件的输入源为空
+
+	// section的层级路径，仅在启用了子section且Type值为Section时才有效，
+	// 具体可参考Reader.AllowSubsection()。
+	Path []string
+
+	// 该节点在输入源中的起始位置和结束位置，不包含首尾的空白字符，
+	// End指向最后一个字符之后的位置。跨越多行的节点，End位于最后一行。
+	// 对于EOF，两者都指向输入源的末尾。
+	Start, End Position
 }
 
 func (t *Token) reset() {
 	t.Type = Undefined
 	t.Value = t.Value[:0]
 	t.Key = t.Key[:0]
+	t.Comment = t.Comment[:0]
+	t.Filename = t.Filename[:0]
+	t.Path = nil
+	t.Start = Position{}
+	t.End = Position{}
 }
 
 // 复制一个新的Token
 func (t *Token) Copy() *Token {
 	return &Token{
-		Type:  t.Type,
-		Value: t.Value,
-		Key:   t.Key,
+		Type:     t.Type,
+		Value:    t.Value,
+		Key:      t.Key,
+		Comment:  t.Comment,
+		Filename: t.Filename,
+		Path:     cloneStrings(t.Path),
+		Start:    t.Start,
+		End:      t.End,
 	}
 }
 
 // ini数据的读取操作类。
-// 注释只支持以`#`,`;`开头的行，不支持行尾注释；
+// 默认只支持以`#`,`;`开头的注释行，默认不支持行尾注释，
+// 可以通过Reader.AllowInlineComment()启用；分隔符、注释前缀等格式可以通过Dialect指定。
 //
 // 对于空格的处理:
 // - section:去掉首尾空格。
 // - comment:去掉尾部空格。
 // - element:去掉key和value的首尾空格
+//
+// 键名和键值都可以使用引号包含，以保留首尾的空格或是特殊字符：
+// - 双引号中的内容支持\n,\t,\r,\",\',\\以及\uXXXX等转义字符；
+// - 单引号中的内容原样保留，不支持任何转义字符。
+// 键值只有在引号完整，且结束的引号之后没有其它内容(空格和行尾注释除外)时，才会作为引号处理，
+// 否则保留原始内容，比如title = "A" & "B"的键值为`"A" & "B"`，title = "Foo的键值为`"Foo`。
+//
+// 键值可以跨越多行，有以下两种方式：
+// - 以`\`结尾的行，将去掉`\`及首尾空格之后，以一个空格与下一行合并，
+//   但下一行为空行、注释、键值对或section，或是已经没有下一行时不作合并，
+//   `\`作为键值的一部分保留，比如path = C:\dir\；
+// - 以`"""`开头的键值，直到以`"""`结尾的行为止，中间的内容原样保留。
+//   紧跟在开始的`"""`之后的换行符，以及结束的`"""`之前的换行符都将被忽略：
+//  key = """
+//  line 1
+//  line 2
+//  """
+//
+// 通过NewReaderFS()或是Reader.SetIncludeFS()指定了fs.FS之后，
+// 还可以通过包含指令引用其它文件的内容，具体可参考Reader.SetIncludeFS()。
 type Reader struct {
-	reader *bufio.Reader
-	atEOF  bool // 已经读取完毕
-	line   int  // 当前正在处理的行数。
-	token  *Token
+	sources    []*source // 输入源，最后一个元素为当前正在读取的输入源。
+	token      *Token
+	dialect    *Dialect
+	inline     bool // 是否支持行尾注释
+	subsection bool // 是否将section名称解析成层级路径
+	tolerant   bool // 是否启用容错模式
+
+	errs SyntaxErrors // 容错模式下收集到的语法错误
+
+	fsys     fs.FS // 包含指令所使用的文件系统，为nil表示不支持包含指令。
+	maxDepth int   // 包含指令的最大嵌套层数
+
+	// 生成当前Token所读取的原始行内容（不包含换行符），
+	// 其中前blanks行为Token之前的空行，以及容错模式下被跳过的行。
+	// raws与lines一一对应，但保留了每一行原本的换行符(\n或\r\n)。
+	lines  []string
+	raws   []string
+	blanks int
+}
+
+// 表示一个输入源
+type source struct {
+	reader   *bufio.Reader
+	closer   io.Closer // 读取完毕之后需要关闭的对象，可以为nil
+	filename string    // 文件名，非文件的输入源为空
+	atEOF    bool      // 已经读取完毕
+	line     int       // 当前正在处理的行数。
+	offset   int       // 已经读取的字节数
+	start    int       // 当前行的起始位置
+	eol      bool      // 当前行是否以换行符结尾
+	prev     int       // 上一行的起始位置
+	raw      string    // 当前行的原始内容，包含换行符。
+	unread   string    // 被撤销读取的行，下次读取时优先返回。
+	depth    int       // 被包含的层数，顶层的输入源为0
+	parent   *source   // 包含当前输入源的输入源
+
+	// 被包含的文件在打开之前，reader为nil，
+	// include为对应的包含指令所在位置的SyntaxError，用于生成打开失败时的错误信息。
+	include *SyntaxError
 }
 
 // 从一个io.Reader初始化Reader
-func NewReader(r io.Reader) *Reader {
-	return &Reader{reader: bufio.NewReader(r), token: &Token{}}
+//
+// d为可选的Dialect，用于指定分隔符、注释前缀等格式，默认为DefaultDialect。
+func NewReader(r io.Reader, d ...*Dialect) *Reader {
+	return newReader(&source{reader: bufio.NewReader(r)}, getDialect(d))
 }
 
 // 从一个[]byte初始化Reader
-func NewReaderBytes(data []byte) *Reader {
-	return NewReader(bytes.NewReader(data))
+func NewReaderBytes(data []byte, d ...*Dialect) *Reader {
+	return NewReader(bytes.NewReader(data), d...)
 }
 
 // 从一个字符串初始化Reader
-func NewReaderString(str string) *Reader {
-	return NewReader(strings.NewReader(str))
+func NewReaderString(str string, d ...*Dialect) *Reader {
+	return NewReader(strings.NewReader(str), d...)
+}
+
+func newReader(src *source, d *Dialect) *Reader {
+	return &Reader{
+		sources:  []*source{src},
+		token:    &Token{},
+		dialect:  d,
+		inline:   d.InlineComment,
+		maxDepth: defaultIncludeDepth,
+	}
+}
+
+// 启用行尾注释。
+//
+// 在键值或是section之后，以空白字符开头的`#`或`;`之后的内容，
+// 将被当作行尾注释，保存在Token.Comment中。引号中的内容不受影响，
+// 若键值中需要包含这些字符，可以使用引号。多行内容不支持行尾注释。
+func (r *Reader) AllowInlineComment() {
+	r.inline = true
+}
+
+// 启用容错模式。
+//
+// 在容错模式下，包含语法错误的行将被跳过，并继续读取之后的内容，
+// 所有的语法错误都会在读取完毕时一起返回，具体可参考Reader.Token()。
+// 读取或是关闭输入源时发生的错误不受影响，依然会直接返回。
+func (r *Reader) Tolerant() {
+	r.tolerant = true
+}
+
+// 启用子section，section名称将按以下格式解析成层级路径，保存在Token.Path中：
+//  [a.b.c]            // ["a", "b", "c"]
+//  [remote "origin"]  // ["remote", "origin"]，引号中的内容可以包含`.`和空格，支持转义字符。
+// Token.Value依然为section的完整名称。路径中包含空的层级，将返回SyntaxError。
+func (r *Reader) AllowSubsection() {
+	r.subsection = true
 }
 
 // 返回下一个Token，当内容读取完毕之后，将返回Type值为EOF的Token。
-// 返回的Token.Value都将不包含尾部的空格（包括换行符）。
+// 除多行内容之外，返回的Token.Value都将不包含尾部的空格（包括换行符）。
 //
 // 返回的Token变量，在下次调用Reader.Token()方法时，数据会被重置，
 // 若需要保存Token的数据，可使用Token.Copy()函数复制一份。
+//
+// 启用了容错模式之后，包含语法错误的行将被跳过，
+// 所有的语法错误都会在返回EOF时，以SyntaxErrors的形式一起返回，此时返回的Token不为nil。
 func (r *Reader) Token() (*Token, error) {
-	r.token.reset()
+	r.lines = r.lines[:0]
+	r.raws = r.raws[:0]
+	r.blanks = 0
 
-START:
-	if r.atEOF {
-		r.token.Type = EOF
-		return r.token, nil
-	}
+	for {
+		token, err := r.next()
+		if err != nil {
+			if r.report(err) == nil {
+				r.blanks = len(r.lines) // 出错的行与空行一样，不属于任何Token。
+				continue
+			}
 
-	buffer, err := r.reader.ReadString('\n')
-	r.line++
-	if err != nil {
-		if err != io.EOF { // 真的发生错误了
+			r.Close() // 无法再继续读取，关闭所有已经打开的文件。
 			return nil, err
 		}
 
-		// 读取完毕
-		r.atEOF = true
-		if len(buffer) == 0 { // 读取完毕，且当前这次也没有新内容
+		if err == nil && token.Type == EOF && len(r.errs) > 0 {
+			return token, r.errs
+		}
+		return token, err
+	}
+}
+
+// 在容错模式下，将SyntaxError类型的err保存到r.errs中，并返回nil，
+// 其它情况下原样返回err。
+func (r *Reader) report(err error) error {
+	var serr *SyntaxError
+	if r.tolerant && errors.As(err, &serr) {
+		r.errs = append(r.errs, serr)
+		return nil
+	}
+	return err
+}
+
+// 读取下一个Token，r.lines中已有的内容将被当作空行处理。
+func (r *Reader) next() (*Token, error) {
+	r.token.reset()
+
+	var line string
+	for {
+		l, ok, err := r.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !ok { // 读取完毕
 			r.token.Type = EOF
+			r.token.Start = r.current().end()
+			r.token.End = r.token.Start
 			return r.token, nil
 		}
+
+		line = strings.TrimSpace(l)
+		if len(line) == 0 {
+			r.blanks++ // 空行
+			continue
+		}
+
+		if r.fsys != nil && isInclude(line) {
+			if err = r.include(line); err != nil {
+				return nil, err
+			}
+			r.lines = r.lines[:len(r.lines)-1] // 包含指令不属于任何Token
+			r.raws = r.raws[:len(r.raws)-1]
+			continue
+		}
+
+		src := r.current()
+		col := len(l) - len(strings.TrimLeftFunc(l, unicode.IsSpace))
+		r.token.Start = Position{Line: src.line, Column: col + 1, Offset: src.start + col}
+		break
+	}
+
+	src := r.current()
+	r.token.Filename = src.filename
+	token, err := r.parseLine(line)
+	if err != nil {
+		return nil, err
+	}
+
+	// 多行内容只会从同一输入源中读取，src.start即为最后一行的起始位置。
+	last := strings.TrimRightFunc(r.lines[len(r.lines)-1], unicode.IsSpace)
+	token.End = Position{Line: src.line, Column: len(last) + 1, Offset: src.start + len(last)}
+	return token, nil
+}
+
+// 当前正在读取的输入源
+func (r *Reader) current() *source {
+	return r.sources[len(r.sources)-1]
+}
+
+// 读取下一行内容，当前输入源读取完毕时，会继续读取上一层的输入源。
+// 当没有更多的内容时，第二个返回值为false。
+func (r *Reader) nextLine() (string, bool, error) {
+	for {
+		if err := r.open(); err != nil {
+			return "", false, err
+		}
+
+		line, ok, err := r.readLine()
+		if err != nil || ok {
+			return line, ok, err
+		}
+
+		// 输入源读取完毕，关闭之后返回上一层。
+		src := r.current()
+		if src.closer != nil {
+			err = src.closer.Close()
+			src.closer = nil
+			if err != nil {
+				return "", false, err
+			}
+		}
+
+		if len(r.sources) == 1 {
+			return line, ok, nil
+		}
+		r.sources = r.sources[:len(r.sources)-1]
+	}
+}
+
+// 从当前输入源中读取一行内容，返回的内容不包含换行符。
+// 当没有更多的内容时，第二个返回值为false。
+func (r *Reader) readLine() (string, bool, error) {
+	src := r.current()
+
+	buffer := src.unread
+	if len(buffer) > 0 {
+		src.unread = ""
+	} else {
+		if src.atEOF {
+			return "", false, nil
+		}
+
+		var err error
+		buffer, err = src.reader.ReadString('\n')
+		if err != nil {
+			if err != io.EOF { // 真的发生错误了
+				return "", false, err
+			}
+
+			// 读取完毕
+			src.atEOF = true
+			if len(buffer) == 0 { // 读取完毕，且当前这次也没有新内容
+				return "", false, nil
+			}
+		}
 	}
 
-	buffer = strings.TrimSpace(buffer)
-	if len(buffer) == 0 { // 空行
-		goto START
+	src.line++
+	src.prev = src.start
+	src.start = src.offset
+	src.offset += len(buffer)
+	src.eol = strings.HasSuffix(buffer, "\n")
+	src.raw = buffer
+	buffer = strings.TrimRight(buffer, "\r\n")
+	r.lines = append(r.lines, buffer)
+	r.raws = append(r.raws, src.raw)
+	return buffer, true, nil
+}
+
+// 撤销最后一次readLine()的操作，下次调用readLine()时将再次返回该行。
+// 只能撤销一次。
+func (r *Reader) unreadLine() {
+	src := r.current()
+	src.unread = src.raw
+	src.line--
+	src.offset = src.start
+	src.start = src.prev
+	src.eol = true // 之后还有内容，上一行必然以换行符结尾。
+	r.lines = r.lines[:len(r.lines)-1]
+	r.raws = r.raws[:len(r.raws)-1]
+}
+
+// 输入源末尾的位置
+func (src *source) end() Position {
+	if src.line == 0 {
+		return Position{Line: 1, Column: 1, Offset: src.offset}
 	}
 
-	return r.parseLine(buffer)
+	if src.eol {
+		return Position{Line: src.line + 1, Column: 1, Offset: src.offset}
+	}
+	return Position{Line: src.line, Column: src.offset - src.start + 1, Offset: src.offset}
+}
+
+// 读取以`"""`开头的多行内容，first为同一行中`"""`之后的内容。
+func (r *Reader) readBlock(first string) (string, error) {
+	if strings.HasSuffix(first, blockDelim) { // 在同一行中结束
+		return first[:len(first)-len(blockDelim)], nil
+	}
+
+	lines := make([]string, 0, 10)
+	if len(first) > 0 {
+		lines = append(lines, first)
+	}
+
+	for {
+		line, ok, err := r.readLine()
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", r.newSyntaxError(r.column(""), "readBlock", ErrUnterminatedBlock)
+		}
+
+		trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+		if strings.HasSuffix(trimmed, blockDelim) {
+			if last := trimmed[:len(trimmed)-len(blockDelim)]; len(strings.TrimSpace(last)) > 0 {
+				lines = append(lines, last)
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+
+		lines = append(lines, line)
+	}
 }
 
 // 将一行字符串转换成对应的Token实例。
 // 返回的Token.Value都将不包含尾部的空格。
 func (r *Reader) parseLine(line string) (*Token, error) {
-	switch line[0] {
-	case '[': // section
+	if p := r.dialect.commentPrefix(line); len(p) > 0 { // comment
+		r.token.Type = Comment
+		r.token.Value = line[len(p):]
+		return r.token, nil
+	}
+
+	if line[0] == '[' { // section
+		if r.inline && line[len(line)-1] != ']' {
+			if i := strings.LastIndexByte(line, ']'); i > 0 {
+				rest := strings.TrimSpace(line[i+1:])
+				if p := r.dialect.commentPrefix(rest); len(p) > 0 {
+					r.token.Comment = rest[len(p):]
+					line = line[:i+1]
+				}
+			}
+		}
+
 		if line[len(line)-1] != ']' {
-			return nil, r.newSyntaxError("parseLine:section名称没有以]作为结尾")
+			return nil, r.newSyntaxError(r.column(""), "parseLine", ErrUnterminatedSection)
 		}
 
-		r.token.Value = strings.TrimSpace(line[1 : len(line)-1])
+		r.token.Value = r.dialect.section(strings.TrimSpace(line[1 : len(line)-1]))
 		if len(r.token.Value) == 0 {
-			return nil, r.newSyntaxError("parseLine:section名称不能为空字符串")
+			return nil, r.newSyntaxError(r.column(line), "parseLine", ErrEmptySectionName)
+		}
+
+		if r.subsection {
+			path, err := splitSection(r.token.Value)
+			if err != nil {
+				return nil, r.newSyntaxError(r.column(line), "parseLine", fmt.Errorf("%w：%v", ErrInvalidSectionName, err))
+			}
+			r.token.Path = path
 		}
 		r.token.Type = Section
 		return r.token, nil
-	case '#', ';': // comment
-		r.token.Type = Comment
-		r.token.Value = line[1:]
-		return r.token, nil
-	default: // element
-		pos := strings.IndexRune(line, '=')
-		if pos < 0 {
-			return nil, r.newSyntaxError("parseLine:表达式中未找到`=`符号")
+	}
+
+	// element
+	key, val, hasValue, err := r.parseKey(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasValue {
+		val = r.dialect.NoValue
+	} else if val, err = r.parseValue(val); err != nil {
+		return nil, err
+	}
+
+	r.token.Type = Element
+	r.token.Key = r.dialect.key(key)
+	r.token.Value = val
+	return r.token, nil
+}
+
+// 从line中分析出键名，返回键名以及分隔符之后的内容。
+// 在允许没有分隔符的键名时，若不存在分隔符，则hasValue为false。
+func (r *Reader) parseKey(line string) (key, rest string, hasValue bool, err error) {
+	if line[0] == '"' || line[0] == '\'' {
+		if key, rest, err = unquote(line); err != nil {
+			return "", "", false, r.newSyntaxError(r.column(line), "parseLine", fmt.Errorf("%w：%v", ErrInvalidQuote, err))
 		}
-		if pos == 0 { // 键名不能为空，键值不能为空
-			return nil, r.newSyntaxError("parseLine:键名不能为空")
+		if len(key) == 0 {
+			return "", "", false, r.newSyntaxError(r.column(line), "parseLine", ErrEmptyKey)
 		}
 
-		r.token.Type = Element
-		r.token.Key = strings.TrimRightFunc(line[:pos], unicode.IsSpace)
-		r.token.Value = strings.TrimLeftFunc(line[pos+1:], unicode.IsSpace)
-		return r.token, nil
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if pos, size := r.dialect.delimiterIndex(rest); pos == 0 {
+			return key, rest[size:], true, nil
+		}
+
+		if r.dialect.AllowNoValue && (len(rest) == 0 || r.inline && len(r.dialect.commentPrefix(rest)) > 0) {
+			r.stripInlineComment(rest)
+			return key, "", false, nil
+		}
+		return "", "", false, r.newSyntaxError(r.column(rest), "parseLine", ErrMissingDelimiter)
 	}
 
-	r.token.Type = EOF
-	return r.token, nil
+	pos, size := r.dialect.delimiterIndex(line)
+	if pos < 0 {
+		if r.dialect.AllowNoValue {
+			return r.stripInlineComment(line), "", false, nil
+		}
+		return "", "", false, r.newSyntaxError(r.column(""), "parseLine", ErrMissingDelimiter)
+	}
+	if pos == 0 { // 键名不能为空，键值不能为空
+		return "", "", false, r.newSyntaxError(r.column(line), "parseLine", ErrEmptyKey)
+	}
+
+	return strings.TrimRightFunc(line[:pos], unicode.IsSpace), line[pos+size:], true, nil
+}
+
+// 分析键值内容，包括引号、多行内容以及以`\`结尾的续行。
+// val为分隔符之后的内容。
+func (r *Reader) parseValue(val string) (string, error) {
+	switch r.dialect.style {
+	case styleGit:
+		return r.parseGitValue(val)
+	case styleSystemd:
+		return r.parseSystemdValue(val)
+	case styleRaw:
+		return strings.TrimSpace(val), nil
+	}
+
+	val = strings.TrimLeftFunc(val, unicode.IsSpace)
+
+	if strings.HasPrefix(val, blockDelim) {
+		return r.readBlock(val[len(blockDelim):])
+	}
+
+	if len(val) > 0 && (val[0] == '"' || val[0] == '\'') {
+		// 引号不完整，或是结束的引号之后还有其它内容时，比如"A" & "B"，
+		// 不作为引号处理，保留原始内容。
+		v, rest, err := unquote(val)
+		rest = strings.TrimSpace(rest)
+		if err == nil && (len(rest) == 0 || (r.inline && len(r.dialect.commentPrefix(rest)) > 0)) {
+			r.stripInlineComment(rest)
+			return v, nil
+		}
+	}
+
+	for strings.HasSuffix(val, "\\") { // 与下一行合并
+		next, ok, err := r.readLine()
+		if err != nil {
+			return "", err
+		}
+
+		// 下一行不是续行时，不作合并，且保留末尾的`\`，比如path = C:\dir\。
+		if !ok {
+			break
+		}
+		next = strings.TrimSpace(next)
+		if !r.isContinuation(next) {
+			r.unreadLine()
+			break
+		}
+
+		val = strings.TrimRightFunc(val[:len(val)-1], unicode.IsSpace)
+		if len(val) > 0 {
+			val += " "
+		}
+		val += next
+	}
+
+	val = r.stripInlineComment(val)
+
+	if r.dialect.IndentContinuation {
+		return r.readIndented(val)
+	}
+	return val, nil
+}
+
+// 去掉首尾空白之后的line是否为`\`之后的续行，空行、注释、section和键值对都不是续行。
+func (r *Reader) isContinuation(line string) bool {
+	if len(line) == 0 || len(r.dialect.commentPrefix(line)) > 0 || line[0] == '[' {
+		return false
+	}
+
+	i, _ := r.dialect.delimiterIndex(line)
+	return i <= 0
+}
+
+// 读取以空白字符开头的续行，并以换行符与val合并。
+func (r *Reader) readIndented(val string) (string, error) {
+	for {
+		line, ok, err := r.readLine()
+		if err != nil || !ok {
+			return val, err
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || !unicode.IsSpace(rune(line[0])) || len(r.dialect.commentPrefix(trimmed)) > 0 {
+			r.unreadLine()
+			return val, nil
+		}
+
+		val += "\n" + trimmed
+	}
+}
+
+// 在启用了行尾注释的情况下，将val中的行尾注释保存到Token.Comment中，
+// 并返回去掉注释之后的内容。
+func (r *Reader) stripInlineComment(val string) string {
+	if !r.inline {
+		return val
+	}
+
+	i := r.dialect.inlineCommentIndex(val)
+	if i < 0 {
+		return val
+	}
+
+	r.token.Comment = val[i+len(r.dialect.commentPrefix(val[i:])):]
+	return strings.TrimRightFunc(val[:i], unicode.IsSpace)
 }
 
 // 构造一个SyntaxError实例。
-func (r *Reader) newSyntaxError(msg string) error {
-	return &SyntaxError{
-		Msg:  msg,
-		Line: r.line,
+//
+// col为错误在当前行中的列号，0表示未知；
+// name为发生错误的函数名称，将与err的内容一起组成SyntaxError.Msg。
+func (r *Reader) newSyntaxError(col int, name string, err error) error {
+	serr := &SyntaxError{
+		Msg:      name + ":" + err.Error(),
+		Line:     r.current().line,
+		Column:   col,
+		Filename: r.current().filename,
+		Err:      err,
+	}
+
+	if len(r.lines) > 0 {
+		serr.Source = r.lines[len(r.lines)-1]
+	}
+	return serr
+}
+
+// 获取s在当前行中的列号，s必须是当前行去掉尾部空白字符之后的后缀，否则返回0。
+// s为空时，返回当前行末尾之后的列号。
+func (r *Reader) column(s string) int {
+	if len(r.lines) == 0 {
+		return 0
+	}
+
+	line := strings.TrimRightFunc(r.lines[len(r.lines)-1], unicode.IsSpace)
+	if !strings.HasSuffix(line, s) {
+		return 0
 	}
+	return len(line) - len(s) + 1
 }
 
 // 将ini转换成map[string]map[string]string格式的数据。其内容表示如下：
@@ -173,43 +766,13 @@ func (r *Reader) newSyntaxError(msg string) error {
 //      "section1" : map[string]string{"k1":"v1", "k2":"v2"},
 //  }
 // 索引值为空的map表示的是非section下的键值对。
+// 包含语法错误的行将被跳过，所有的语法错误以SyntaxErrors的形式一起返回。
+// 重复的键名以最后一个键值为准，同名的section会被合并，
+// 若需要其它的处理方式，可以使用UnmarshalMapDuplicate()或是UnmarshalMultiMap()。
+// [child : parent]形式的section继承，可以在之后调用Inherit()处理。
 //
-// 没有与之相对就的MarshalMap，因为map是无序的，若一个map带了section，
-// 则转换结果未必是正确的。
+// 由于map是无序的，与之对应的MarshalMap()会按字母顺序输出section和键名，
+// 若需要在读写之间保持原有的顺序，可以使用UnmarshalOrdered()和MarshalOrdered()。
 func UnmarshalMap(data []byte) (map[string]map[string]string, error) {
-	if len(data) == 0 {
-		return nil, &SyntaxError{Msg: "UnmarshalMap:没有内容", Line: 0}
-	}
-
-	m := make(map[string]map[string]string)
-	currSection := map[string]string{}
-	sectionName := ""
-
-	r := NewReaderBytes(data)
-LOOP:
-	for {
-		token, err := r.Token()
-		if err != nil {
-			return nil, err
-		}
-
-		switch token.Type {
-		case Comment:
-			continue
-		case EOF:
-			break LOOP
-		case Element:
-			currSection[token.Key] = token.Value
-		case Section:
-			m[sectionName] = currSection
-
-			currSection = map[string]string{}
-			sectionName = token.Value
-		default:
-			return nil, errors.New("UnmarshalMap:未知的元素类型")
-		}
-	} // end for
-	m[sectionName] = currSection
-
-	return m, nil
+	return UnmarshalMapDuplicate(data, DuplicateLast)
 }
@@ -5,6 +5,7 @@
 package ini
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"unicode"
@@ -16,9 +17,10 @@ func TestToken(t *testing.T) {
 	a := assert.New(t)
 
 	token := &Token{
-		Type:  Element,
-		Key:   "key",
-		Value: "value",
+		Type:    Element,
+		Key:     "key",
+		Value:   "value",
+		Comment: "comment",
 	}
 
 	t1 := token.Copy()
@@ -28,6 +30,7 @@ func TestToken(t *testing.T) {
 	a.Equal(token.Type, Undefined).Equal(t1.Type, Element)
 	a.Equal(token.Key, "").Equal(t1.Key, "key")
 	a.Equal(token.Value, "").Equal(t1.Value, "value")
+	a.Equal(token.Comment, "").Equal(t1.Comment, "comment")
 }
 
 func TestReader_ParseLine(t *testing.T) {
@@ -50,12 +53,27 @@ func TestReader_ParseLine(t *testing.T) {
 		&test{value: "key = v al", isError: false, token: &Token{Type: Element, Key: "key", Value: "v al"}},
 		&test{value: "key = v=al", isError: false, token: &Token{Type: Element, Key: "key", Value: "v=al"}},
 		&test{value: "key =", isError: false, token: &Token{Type: Element, Key: "key", Value: ""}},
+		&test{value: `key = " v al "`, isError: false, token: &Token{Type: Element, Key: "key", Value: " v al "}},
+		&test{value: `key = "\t\"\u4e2d"`, isError: false, token: &Token{Type: Element, Key: "key", Value: "\t\"中"}},
+		&test{value: `key = '\t'`, isError: false, token: &Token{Type: Element, Key: "key", Value: `\t`}},
+		&test{value: `" k=ey " = val`, isError: false, token: &Token{Type: Element, Key: " k=ey ", Value: "val"}},
+		&test{value: `'#key'=val`, isError: false, token: &Token{Type: Element, Key: "#key", Value: "val"}},
+		&test{value: `greeting = "hello" world`, isError: false, token: &Token{Type: Element, Key: "greeting", Value: `"hello" world`}},
+		&test{value: `title = "A" & "B"`, isError: false, token: &Token{Type: Element, Key: "title", Value: `"A" & "B"`}},
+		&test{value: `title = 'A' "B"`, isError: false, token: &Token{Type: Element, Key: "title", Value: `'A' "B"`}},
+		&test{value: `key = "val`, isError: false, token: &Token{Type: Element, Key: "key", Value: `"val`}},
+		&test{value: `name = 'n Sync`, isError: false, token: &Token{Type: Element, Key: "name", Value: `'n Sync`}},
+		&test{value: `key = "\x"`, isError: false, token: &Token{Type: Element, Key: "key", Value: `"\x"`}},
+		&test{value: `key = v"al"`, isError: false, token: &Token{Type: Element, Key: "key", Value: `v"al"`}},
 
 		// 各类错误格式
 		&test{value: "[section", isError: true},
 		&test{value: "key val", isError: true},
 		&test{value: "[]", isError: true},
 		&test{value: "=i", isError: true},
+		&test{value: `"key" val`, isError: true},
+		&test{value: `"" = val`, isError: true},
+		&test{value: `"key = val`, isError: true},
 	}
 
 	r := NewReader(nil)
@@ -183,4 +201,291 @@ func TestUnmarshalMap(t *testing.T) {
 	m, err = UnmarshalMap(str)
 	a.NotError(err)
 	a.Equal(m, v1)
+
+	// 同名的section会被合并，重复的键名以最后一个为准
+	str = []byte(`
+	[section]
+	k1=v1
+	k2=v2
+	[section]
+	k2=v3
+	`)
+	v1 = map[string]map[string]string{
+		"":        map[string]string{},
+		"section": map[string]string{"k1": "v1", "k2": "v3"},
+	}
+	m, err = UnmarshalMap(str)
+	a.NotError(err)
+	a.Equal(m, v1)
+}
+
+func TestReader_MultiLine(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewReaderString(`key1 = line 1 \
+    line 2\
+line 3
+key2 = """
+  line 1
+line 2  
+
+"""
+key3 = """line 1
+line 2"""
+key4 = """single line"""
+key5 = end \
+`)
+
+	test := func(key, val string) {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		a.Equal(token.Type, Element).
+			Equal(token.Key, key).
+			Equal(token.Value, val)
+	}
+	test("key1", "line 1 line 2 line 3")
+	test("key2", "  line 1\nline 2  \n")
+	test("key3", "line 1\nline 2")
+	test("key4", "single line")
+	test("key5", `end \`)
+
+	token, err := r.Token()
+	a.NotError(err).Equal(token.Type, EOF)
+
+	// 未结束的多行内容
+	r = NewReaderString("key = \"\"\"\nline 1\n")
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+
+	// 下一行不是续行时，不作合并
+	m, err := UnmarshalMap([]byte(`path = C:\dir\
+other = x
+[s]
+k = a\
+[t]
+k = b \
+  c = d
+comment = C:\dir\
+; comment
+empty = C:\dir\
+
+last = C:\dir\`))
+	a.NotError(err)
+	a.Equal(m, map[string]map[string]string{
+		"":  {"path": `C:\dir\`, "other": "x"},
+		"s": {"k": `a\`},
+		"t": {"k": `b \`, "c": "d", "comment": `C:\dir\`, "empty": `C:\dir\`, "last": `C:\dir\`},
+	})
+}
+
+func TestReader_AllowInlineComment(t *testing.T) {
+	a := assert.New(t)
+
+	data := `port = 8080 ; default
+path = /a;b#c
+empty = ;comment
+quoted = " ; x " # comment
+[section] # section comment
+`
+
+	// 默认不支持行尾注释
+	r := NewReaderString(data)
+	token, err := r.Token()
+	a.NotError(err).
+		Equal(token.Value, "8080 ; default").
+		Equal(token.Comment, "")
+
+	r = NewReaderString(data)
+	r.AllowInlineComment()
+	test := func(typ int, key, val, comment string) {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		a.Equal(token.Type, typ).
+			Equal(token.Key, key).
+			Equal(token.Value, val).
+			Equal(token.Comment, comment)
+	}
+	test(Element, "port", "8080", " default")
+	test(Element, "path", "/a;b#c", "")
+	test(Element, "empty", "", "comment")
+	test(Element, "quoted", " ; x ", " comment")
+	test(Section, "", "section", " section comment")
+
+	// 引号之后还有除注释以外的内容，按原始内容处理
+	r = NewReaderString(`key = "val" x # comment`)
+	r.AllowInlineComment()
+	test(Element, "key", `"val" x`, " comment")
+}
+
+func TestReader_AllowSubsection(t *testing.T) {
+	a := assert.New(t)
+
+	data := `[server]
+[server.http]
+[ a . b ]
+[remote "origin"]
+[remote "a.b \"c\""]
+[branch.dev "feature/x"]
+`
+
+	// 默认不解析层级路径
+	r := NewReaderString(data)
+	token, err := r.Token()
+	a.NotError(err).Nil(token.Path)
+
+	r = NewReaderString(data)
+	r.AllowSubsection()
+	test := func(val string, path ...string) {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		a.Equal(token.Type, Section).
+			Equal(token.Value, val).
+			Equal(token.Path, path)
+	}
+	test("server", "server")
+	test("server.http", "server", "http")
+	test("a . b", "a", "b")
+	test(`remote "origin"`, "remote", "origin")
+	test(`remote "a.b \"c\""`, "remote", `a.b "c"`)
+	test(`branch.dev "feature/x"`, "branch", "dev", "feature/x")
+
+	// 无效的层级路径
+	for _, s := range []string{"[a..b]", "[.a]", `[remote "origin]`, `[remote "origin" x]`} {
+		r = NewReaderString(s)
+		r.AllowSubsection()
+		token, err = r.Token()
+		a.Error(err).Nil(token)
+	}
+}
+
+func TestReader_Position(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewReaderString("  [section]  \r\n\nkey = a \\\n  b\n#中文\nlast=1")
+	test := func(typ int, start, end Position) {
+		token, err := r.Token()
+		a.NotError(err).NotNil(token)
+		a.Equal(token.Type, typ).
+			Equal(token.Start, start).
+			Equal(token.End, end)
+	}
+	test(Section, Position{Line: 1, Column: 3, Offset: 2}, Position{Line: 1, Column: 12, Offset: 11})
+	test(Element, Position{Line: 3, Column: 1, Offset: 16}, Position{Line: 4, Column: 4, Offset: 29})
+	test(Comment, Position{Line: 5, Column: 1, Offset: 30}, Position{Line: 5, Column: 8, Offset: 37})
+	test(Element, Position{Line: 6, Column: 1, Offset: 38}, Position{Line: 6, Column: 7, Offset: 44})
+	test(EOF, Position{Line: 6, Column: 7, Offset: 44}, Position{Line: 6, Column: 7, Offset: 44})
+
+	// 以换行符结尾
+	r = NewReaderString("k=v\n")
+	test(Element, Position{Line: 1, Column: 1, Offset: 0}, Position{Line: 1, Column: 4, Offset: 3})
+	test(EOF, Position{Line: 2, Column: 1, Offset: 4}, Position{Line: 2, Column: 1, Offset: 4})
+
+	// 空内容
+	r = NewReaderString("")
+	test(EOF, Position{Line: 1, Column: 1, Offset: 0}, Position{Line: 1, Column: 1, Offset: 0})
+
+	// Copy
+	r = NewReaderString("k=v")
+	token, err := r.Token()
+	a.NotError(err)
+	a.Equal(token.Copy().End, token.End)
+}
+
+func TestSyntaxError(t *testing.T) {
+	a := assert.New(t)
+
+	test := func(data string, target error, line, col int, excerpt string) {
+		r := NewReaderString(data)
+		token, err := r.Token()
+		for err == nil && token.Type != EOF {
+			token, err = r.Token()
+		}
+		a.Error(err).Nil(token)
+		a.True(errors.Is(err, target), "%v", err)
+
+		var serr *SyntaxError
+		a.True(errors.As(err, &serr))
+		a.Equal(serr.Line, line).
+			Equal(serr.Column, col).
+			Equal(serr.Excerpt(), excerpt)
+	}
+
+	test("  [section", ErrUnterminatedSection, 1, 11, "  [section\n          ^")
+	test("\t[ ]", ErrEmptySectionName, 1, 2, "\t[ ]\n\t^")
+	test("k=v\nkey val", ErrMissingDelimiter, 2, 8, "key val\n       ^")
+	test("k=v\n  = val", ErrEmptyKey, 2, 3, "  = val\n  ^")
+	test(`"key" val`, ErrMissingDelimiter, 1, 7, "\"key\" val\n      ^")
+	test(`"k = v`, ErrInvalidQuote, 1, 1, "\"k = v\n^")
+	test("k = \"\"\"\nline", ErrUnterminatedBlock, 2, 5, "line\n    ^")
+
+	serr := &SyntaxError{Line: 5, Column: 3, Msg: "msg", Filename: "a.ini", Source: "abc"}
+	a.Equal(serr.Error(), "encoding/ini，在a.ini的第5行第3列发生语法错误：msg")
+	a.Nil(serr.Unwrap())
+	serr = &SyntaxError{Line: 5, Msg: "msg", Source: "abc"}
+	a.Equal(serr.Error(), "encoding/ini，在第5行发生语法错误：msg")
+	a.Equal(serr.Excerpt(), "abc")
+
+	// 没有行号
+	serr = &SyntaxError{Msg: "msg"}
+	a.Equal(serr.Error(), "encoding/ini，msg")
+	serr = &SyntaxError{Msg: "msg", Filename: "a.ini"}
+	a.Equal(serr.Error(), "encoding/ini，在a.ini中发生错误：msg")
+}
+
+func TestReader_Tolerant(t *testing.T) {
+	a := assert.New(t)
+
+	data := `k1 = v1
+[section
+k2 = v2
+invalid line
+= empty
+k3 = v3
+`
+
+	// 默认在第一个错误处停止
+	r := NewReaderString(data)
+	token, err := r.Token()
+	a.NotError(err).Equal(token.Key, "k1")
+	token, err = r.Token()
+	a.Error(err).Nil(token)
+
+	r = NewReaderString(data)
+	r.Tolerant()
+	keys := []string{}
+	for {
+		token, err = r.Token()
+		if token.Type == EOF {
+			break
+		}
+		a.NotError(err)
+		keys = append(keys, token.Key)
+	}
+	a.Equal(keys, []string{"k1", "k2", "k3"})
+
+	errs, ok := err.(SyntaxErrors)
+	a.True(ok).Equal(len(errs), 3)
+	a.Equal(errs[0].Line, 2).True(errors.Is(errs[0], ErrUnterminatedSection))
+	a.Equal(errs[1].Line, 4).True(errors.Is(errs[1], ErrMissingDelimiter))
+	a.Equal(errs[2].Line, 5).True(errors.Is(errs[2], ErrEmptyKey))
+
+	// errors.Is和errors.As
+	a.True(errors.Is(err, ErrEmptyKey))
+	var serr *SyntaxError
+	a.True(errors.As(err, &serr)).Equal(serr.Line, 2)
+	a.Equal(len(strings.Split(err.Error(), "\n")), 3)
+
+	// 没有错误
+	r = NewReaderString("k=v")
+	r.Tolerant()
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Key, "k")
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Type, EOF)
+
+	// UnmarshalMap返回所有的错误
+	m, err := UnmarshalMap([]byte(data))
+	a.Error(err).Nil(m)
+	errs, ok = err.(SyntaxErrors)
+	a.True(ok).Equal(len(errs), 3)
 }
@@ -0,0 +1,495 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema.Validate()返回的各类错误，可以通过errors.Is()判断具体的错误类型。
+var (
+	ErrUnknownSection = errors.New("未知的section")
+	ErrUnknownKey     = errors.New("未知的键名")
+	ErrRequired       = errors.New("缺少必需的内容")
+	ErrInvalidValue   = errors.New("无效的值")
+	ErrDeprecated     = errors.New("已经废弃的键名")
+)
+
+// ValueType表示KeySchema中值的类型。
+type ValueType int
+
+// 值的类型
+const (
+	TypeString   ValueType = iota // 字符串，默认值
+	TypeInt                       // 整数，支持0x等进制前缀
+	TypeUint                      // 无符号整数，支持0x等进制前缀
+	TypeFloat                     // 浮点数
+	TypeBool                      // 布尔值，规则与ConfigSection.Bool()相同
+	TypeDuration                  // time.Duration
+)
+
+var valueTypeNames = map[ValueType]string{
+	TypeString:   "string",
+	TypeInt:      "int",
+	TypeUint:     "uint",
+	TypeFloat:    "float",
+	TypeBool:     "bool",
+	TypeDuration: "duration",
+}
+
+func (t ValueType) String() string {
+	if name, found := valueTypeNames[t]; found {
+		return name
+	}
+	return "<unknown>"
+}
+
+// Schema描述ini文件中允许出现的section和键名，用于在部署之前检测配置文件中的错误。
+//
+// 可以直接在Go中声明，也可以通过LoadSchema()从ini格式的描述文件中加载。
+type Schema struct {
+	Sections []*SectionSchema // 各section的描述，Name为空的表示全局的键值对
+
+	// 是否允许出现Sections中未声明的section，
+	// 未声明全局键值对的描述时，全局的键值对同样受此值的影响。
+	AllowUnknownSections bool
+}
+
+// SectionSchema描述一个section。
+type SectionSchema struct {
+	// section名称，可以包含path.Match()支持的通配符，
+	// 比如remote "*"匹配所有的[remote "xxx"]。
+	Name string
+
+	Required         bool // section是否必须存在
+	AllowUnknownKeys bool // 是否允许出现Keys中未声明的键名
+	Keys             []*KeySchema
+}
+
+// KeySchema描述一个键值对。
+type KeySchema struct {
+	Name     string
+	Type     ValueType
+	Required bool
+
+	// 取值范围，按Type解析，为空表示不限制，
+	// 比如Type为TypeDuration时可以是1s，TypeString时则表示字符串的长度。
+	// TypeBool不支持取值范围。
+	Min, Max string
+
+	Enum    []string // 可选的值，为空表示不限制
+	Pattern string   // 值需要匹配的正则表达式，为空表示不限制
+
+	// 不为空表示该键名已经被废弃，其内容为提示信息，比如：请使用xx代替。
+	Deprecated string
+}
+
+// KeySchema编译之后的规则。
+//
+// 不保存在KeySchema中，以保证Schema.Validate()可以并发调用。
+type keyRule struct {
+	*KeySchema
+	pattern  *regexp.Regexp
+	min, max *float64
+}
+
+// 从r中加载Schema。描述文件的格式如下：
+//  @allow-unknown-sections = true
+//  name = string; required
+//
+//  [server]
+//  @required = true
+//  @allow-unknown = true
+//  port = int; required; min=1; max=65535
+//  mode = string; enum=dev,prod
+//  timeout = duration; min=1s
+//  host = string; pattern=^[a-z.]+$
+//  addr = string; deprecated=请使用host代替
+//
+//  [remote "*"]
+//  url = string; required
+// 以@开头的键名为设置项：全局的@allow-unknown-sections对应于Schema.AllowUnknownSections，
+// section中的@required和@allow-unknown分别对应于SectionSchema.Required和SectionSchema.AllowUnknownKeys；
+// 其它键名则是对应键值对的规则。
+//
+// 键值为以分号分隔的规则，类型必须在最前面，省略则表示string，
+// pattern必须在最后，其之后的所有内容都将被当作正则表达式。
+// 全局的键值对为空且未设置@allow-unknown时，表示不允许出现全局的键值对。
+func LoadSchema(r *Reader) (*Schema, error) {
+	f, err := LoadFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{Sections: []*SectionSchema{}}
+	for _, fs := range append([]*FileSection{f.Global}, f.Sections...) {
+		ss := &SectionSchema{Name: fs.Name, Keys: []*KeySchema{}}
+
+		for _, k := range fs.Keys {
+			switch {
+			case k.Name == "@allow-unknown-sections" && len(fs.Name) == 0:
+				s.AllowUnknownSections, err = parseBool(k.Value)
+			case k.Name == "@required":
+				ss.Required, err = parseBool(k.Value)
+			case k.Name == "@allow-unknown":
+				ss.AllowUnknownKeys, err = parseBool(k.Value)
+			case strings.HasPrefix(k.Name, "@"):
+				err = fmt.Errorf("未知的设置项%v", k.Name)
+			default:
+				var ks *KeySchema
+				if ks, err = parseKeySchema(k.Name, k.Value); err == nil {
+					ss.Keys = append(ss.Keys, ks)
+				}
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("LoadSchema:第%d行的内容无效：%v", k.line, err)
+			}
+		}
+
+		if len(fs.Name) > 0 || len(ss.Keys) > 0 || ss.AllowUnknownKeys {
+			s.Sections = append(s.Sections, ss)
+		}
+	}
+
+	if _, err = s.compile(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 解析描述文件中的规则
+func parseKeySchema(name, rule string) (*KeySchema, error) {
+	ks := &KeySchema{Name: name}
+
+	parts := strings.Split(rule, ";")
+	for i := 0; i < len(parts); i++ {
+		part := strings.TrimSpace(parts[i])
+		if len(part) == 0 {
+			continue
+		}
+
+		var arg string
+		if j := strings.IndexByte(part, '='); j > -1 {
+			part, arg = strings.TrimSpace(part[:j]), strings.TrimSpace(part[j+1:])
+		}
+
+		switch part {
+		case "required":
+			ks.Required = true
+		case "min":
+			ks.Min = arg
+		case "max":
+			ks.Max = arg
+		case "enum":
+			for _, v := range strings.Split(arg, ",") {
+				ks.Enum = append(ks.Enum, strings.TrimSpace(v))
+			}
+		case "pattern": // 之后的所有内容都属于正则表达式
+			ks.Pattern = strings.TrimSpace(arg + strings.Join(append([]string{""}, parts[i+1:]...), ";"))
+			i = len(parts)
+		case "deprecated":
+			ks.Deprecated = arg
+			if len(arg) == 0 {
+				ks.Deprecated = "已经废弃"
+			}
+		default:
+			if i > 0 || len(arg) > 0 {
+				return nil, fmt.Errorf("未知的规则%v", part)
+			}
+
+			found := false
+			for t, n := range valueTypeNames {
+				if n == part {
+					ks.Type, found = t, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("未知的类型%v", part)
+			}
+		}
+	}
+
+	return ks, nil
+}
+
+// 检测Schema本身的内容，并编译其中的正则表达式和取值范围。
+func (s *Schema) compile() (map[*KeySchema]*keyRule, error) {
+	rules := make(map[*KeySchema]*keyRule)
+	for _, ss := range s.Sections {
+		if _, err := path.Match(ss.Name, ""); err != nil {
+			return nil, fmt.Errorf("Schema:无效的section名称%v：%v", ss.Name, err)
+		}
+
+		for _, ks := range ss.Keys {
+			rule, err := ks.compile()
+			if err != nil {
+				return nil, fmt.Errorf("Schema:[%v]中%v的描述无效：%v", ss.Name, ks.Name, err)
+			}
+			rules[ks] = rule
+		}
+	}
+
+	return rules, nil
+}
+
+func (ks *KeySchema) compile() (rule *keyRule, err error) {
+	rule = &keyRule{KeySchema: ks}
+	if len(ks.Pattern) > 0 {
+		if rule.pattern, err = regexp.Compile(ks.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	if ks.Type == TypeBool && (len(ks.Min) > 0 || len(ks.Max) > 0) {
+		return nil, errors.New("布尔类型不支持取值范围")
+	}
+
+	parse := func(val string) (*float64, error) {
+		if len(val) == 0 {
+			return nil, nil
+		}
+
+		var n float64
+		var err error
+		if ks.Type == TypeString {
+			var i int
+			i, err = strconv.Atoi(val)
+			n = float64(i)
+		} else {
+			n, err = ks.number(val)
+		}
+		return &n, err
+	}
+
+	if rule.min, err = parse(ks.Min); err != nil {
+		return nil, err
+	}
+	if rule.max, err = parse(ks.Max); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// 将val按Type转换成数值，用于比较取值范围。
+func (ks *KeySchema) number(val string) (float64, error) {
+	switch ks.Type {
+	case TypeInt:
+		n, err := strconv.ParseInt(val, 0, 64)
+		return float64(n), err
+	case TypeUint:
+		n, err := strconv.ParseUint(val, 0, 64)
+		return float64(n), err
+	case TypeFloat:
+		return strconv.ParseFloat(val, 64)
+	case TypeDuration:
+		d, err := time.ParseDuration(val)
+		return float64(d), err
+	case TypeString:
+		return float64(len(val)), nil
+	case TypeBool:
+		_, err := parseBool(val)
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("未知的类型%v", ks.Type)
+}
+
+// 检测值val，返回不符合的原因，符合要求则返回空字符串。
+func (rule *keyRule) check(val string) string {
+	n, err := rule.number(val)
+	if err != nil {
+		return fmt.Sprintf("无法转换成%v类型", rule.Type)
+	}
+
+	switch {
+	case rule.min != nil && n < *rule.min:
+		return "小于最小值" + rule.Min
+	case rule.max != nil && n > *rule.max:
+		return "大于最大值" + rule.Max
+	case rule.pattern != nil && !rule.pattern.MatchString(val):
+		return "不匹配" + rule.Pattern
+	}
+
+	if len(rule.Enum) > 0 {
+		for _, e := range rule.Enum {
+			if e == val {
+				return ""
+			}
+		}
+		return "只能是" + strings.Join(rule.Enum, "、") + "中的一个"
+	}
+
+	return ""
+}
+
+// 查找与name匹配的section描述，不存在则返回nil。
+// 优先返回名称完全相同的，之后才是通配符匹配的。
+func (s *Schema) section(name string) *SectionSchema {
+	for _, ss := range s.Sections {
+		if ss.Name == name {
+			return ss
+		}
+	}
+
+	for _, ss := range s.Sections {
+		if ok, _ := path.Match(ss.Name, name); ok && len(name) > 0 {
+			return ss
+		}
+	}
+
+	return nil
+}
+
+// 查找名称为name的键名描述，不存在则返回nil。
+func (ss *SectionSchema) key(name string) *KeySchema {
+	for _, ks := range ss.Keys {
+		if ks.Name == name {
+			return ks
+		}
+	}
+
+	return nil
+}
+
+// 从r中读取内容并检测是否符合s的描述，具体可参考Schema.Validate()。
+// 若r启用了容错模式，语法错误也将包含在返回的SyntaxErrors中。
+func (s *Schema) ValidateReader(r *Reader) error {
+	f, err := LoadFile(r)
+	if f == nil {
+		return err
+	}
+
+	var errs SyntaxErrors
+	errors.As(err, &errs)
+
+	if err = s.Validate(f); err != nil {
+		var verrs SyntaxErrors
+		if !errors.As(err, &verrs) {
+			return err
+		}
+		errs = append(errs, verrs...)
+	}
+
+	if len(errs) > 0 {
+		sortSyntaxErrors(errs)
+		return errs
+	}
+	return nil
+}
+
+// 检测f是否符合s的描述，所有的问题以SyntaxErrors的形式一起返回，按行号排序，
+// 每一个SyntaxError的Err字段为ErrUnknownSection、ErrUnknownKey、ErrRequired、
+// ErrInvalidValue和ErrDeprecated中的一个，缺少的section行号为0。
+// 同名的section会被当作一个整体检测。
+//
+// s本身的内容有误时，返回普通的错误信息。可以在多个goroutine中同时调用，
+// 但调用期间不能修改s的内容。
+func (s *Schema) Validate(f *File) error {
+	rules, err := s.compile()
+	if err != nil {
+		return err
+	}
+
+	var errs SyntaxErrors
+	report := func(line int, err error, format string, v ...interface{}) {
+		errs = append(errs, &SyntaxError{
+			Line: line,
+			Msg:  "Validate:" + fmt.Sprintf(format, v...) + "：" + err.Error(),
+			Err:  err,
+		})
+	}
+
+	// 按名称合并section
+	names := []string{""}
+	groups := map[string][]*FileSection{"": []*FileSection{f.Global}}
+	for _, fs := range f.Sections {
+		if _, found := groups[fs.Name]; !found {
+			names = append(names, fs.Name)
+		}
+		groups[fs.Name] = append(groups[fs.Name], fs)
+	}
+
+	seen := make(map[*SectionSchema]bool, len(s.Sections))
+	for _, name := range names {
+		sections := groups[name]
+		ss := s.section(name)
+		if ss == nil {
+			if s.AllowUnknownSections {
+				continue
+			}
+
+			if len(name) > 0 {
+				report(sections[0].line, ErrUnknownSection, "[%v]", name)
+				continue
+			}
+			for _, k := range f.Global.Keys { // 未声明全局键值对的描述
+				report(k.line, ErrUnknownKey, "%v", k.Name)
+			}
+			continue
+		}
+		seen[ss] = true
+
+		where := "" // 全局的键值对不显示section名称
+		if len(name) > 0 {
+			where = "[" + name + "]中的"
+		}
+
+		present := make(map[string]bool)
+		for _, fs := range sections {
+			for _, k := range fs.Keys {
+				present[k.Name] = true
+
+				ks := ss.key(k.Name)
+				if ks == nil {
+					if !ss.AllowUnknownKeys {
+						report(k.line, ErrUnknownKey, "%v%v", where, k.Name)
+					}
+					continue
+				}
+
+				if len(ks.Deprecated) > 0 {
+					report(k.line, ErrDeprecated, "%v%v，%v", where, k.Name, ks.Deprecated)
+				}
+				if msg := rules[ks].check(k.Value); len(msg) > 0 {
+					report(k.line, ErrInvalidValue, "%v%v的值%v%v", where, k.Name, k.Value, msg)
+				}
+			}
+		}
+
+		for _, ks := range ss.Keys {
+			if ks.Required && !present[ks.Name] {
+				report(sections[0].line, ErrRequired, "%v%v", where, ks.Name)
+			}
+		}
+	}
+
+	for _, ss := range s.Sections {
+		if ss.Required && !seen[ss] && len(ss.Name) > 0 {
+			report(0, ErrRequired, "[%v]", ss.Name)
+		}
+	}
+
+	if len(errs) > 0 {
+		sortSyntaxErrors(errs)
+		return errs
+	}
+	return nil
+}
+
+// 按行号排序，行号相同的保持原有的顺序。
+func sortSyntaxErrors(errs SyntaxErrors) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+const schemaTestData = `
+name = string; required
+
+[server]
+@required = true
+port = int; required; min=1; max=65535
+mode = string; enum=dev,prod
+timeout = duration; min=1s; max=1m
+host = string; pattern=^[a-z.]+;?$
+addr = deprecated=请使用host代替
+debug = bool
+
+[remote "*"]
+@allow-unknown = yes
+url = string; required; min=3
+`
+
+func loadTestSchema(a *assert.Assertion) *Schema {
+	s, err := LoadSchema(NewReaderString(schemaTestData))
+	a.NotError(err).NotNil(s)
+	return s
+}
+
+func TestLoadSchema(t *testing.T) {
+	a := assert.New(t)
+
+	s := loadTestSchema(a)
+	a.False(s.AllowUnknownSections).Equal(len(s.Sections), 3)
+
+	global := s.section("")
+	a.NotNil(global).Equal(len(global.Keys), 1)
+	a.True(global.Keys[0].Required).Equal(global.Keys[0].Type, TypeString)
+
+	server := s.section("server")
+	a.NotNil(server).True(server.Required).False(server.AllowUnknownKeys)
+	port := server.key("port")
+	a.NotNil(port).Equal(port.Type, TypeInt).True(port.Required)
+	a.Equal(port.Min, "1").Equal(port.Max, "65535")
+	a.Equal(server.key("mode").Enum, []string{"dev", "prod"})
+	a.Equal(server.key("host").Pattern, "^[a-z.]+;?$")
+	a.Equal(server.key("addr").Deprecated, "请使用host代替")
+	a.Equal(server.key("debug").Type, TypeBool)
+
+	remote := s.section(`remote "origin"`)
+	a.NotNil(remote).True(remote.AllowUnknownKeys)
+	a.Nil(s.section("none"))
+
+	// 未声明全局键值对
+	s, err := LoadSchema(NewReaderString("@allow-unknown-sections = true\n[s]\nk=int"))
+	a.NotError(err).True(s.AllowUnknownSections).Equal(len(s.Sections), 1)
+
+	// 文档中的示例
+	s, err = LoadSchema(NewReaderString(`@allow-unknown-sections = true
+name = string; required
+
+[server]
+@required = true
+@allow-unknown = true
+port = int; required; min=1; max=65535
+mode = string; enum=dev,prod
+timeout = duration; min=1s
+host = string; pattern=^[a-z.]+$
+addr = string; deprecated=请使用host代替
+
+[remote "*"]
+url = string; required
+`))
+	a.NotError(err).True(s.AllowUnknownSections).Equal(len(s.Sections), 3)
+	a.True(s.section("server").AllowUnknownKeys).
+		Equal(s.section("server").key("host").Pattern, "^[a-z.]+$")
+
+	// 无效的描述
+	for _, data := range []string{
+		"k = unknown",
+		"k = int; unknown",
+		"k = required; int",
+		"k = int; min=abc",
+		"k = bool; max=1",
+		"k = string; pattern=[",
+		"@unknown = true",
+		"[s]\n@required = abc",
+		"[s",
+	} {
+		s, err := LoadSchema(NewReaderString(data))
+		a.Error(err, data).Nil(s)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	a := assert.New(t)
+	s := loadTestSchema(a)
+
+	data := `name = app
+[server]
+port = 8080
+mode = dev
+timeout = 5s
+host = example.com
+debug = on
+
+[remote "origin"]
+url = git://example.com
+other = 1
+`
+	a.NotError(s.ValidateReader(NewReaderString(data)))
+
+	data = `unknown = 1
+[server]
+port = 0
+mode = test
+timeout = 1h
+host = Example.com
+addr = example.com
+debug = abc
+other = 1
+
+[remote "origin"]
+fetch = 1
+
+[unknown]
+[server]
+port = 70000
+`
+	err := s.ValidateReader(NewReaderString(data))
+	a.Error(err)
+	var errs SyntaxErrors
+	a.True(errors.As(err, &errs))
+
+	type result struct {
+		line int
+		err  error
+		key  string
+	}
+	results := []result{
+		{0, ErrRequired, "name"},
+		{1, ErrUnknownKey, "unknown"},
+		{3, ErrInvalidValue, "port"},
+		{4, ErrInvalidValue, "mode"},
+		{5, ErrInvalidValue, "timeout"},
+		{6, ErrInvalidValue, "host"},
+		{7, ErrDeprecated, "addr"},
+		{8, ErrInvalidValue, "debug"},
+		{9, ErrUnknownKey, "other"},
+		{11, ErrRequired, "url"},
+		{14, ErrUnknownSection, "unknown"},
+		{16, ErrInvalidValue, "port"},
+	}
+	a.Equal(len(errs), len(results), err)
+	for i, r := range results {
+		a.Equal(errs[i].Line, r.line, errs[i])
+		a.True(errors.Is(errs[i], r.err), errs[i])
+		a.True(strings.Contains(errs[i].Msg, r.key), errs[i])
+	}
+	a.Equal(errs[1].Msg, "Validate:unknown："+ErrUnknownKey.Error())
+	a.Equal(errs[8].Msg, "Validate:[server]中的other："+ErrUnknownKey.Error())
+
+	// 缺少section，未声明全局键值对
+	s = &Schema{Sections: []*SectionSchema{
+		{Name: "s", Required: true},
+		{Name: "o*", Keys: []*KeySchema{{Name: "size", Type: TypeUint, Max: "0x10"}}},
+	}}
+	err = s.ValidateReader(NewReaderString("k=1\n[other]\nsize=17\n[o]\nsize=-1"))
+	errs = nil
+	a.True(errors.As(err, &errs)).Equal(len(errs), 4)
+	a.Equal(errs[0].Line, 0).True(errors.Is(errs[0], ErrRequired))
+	a.False(strings.Contains(errs[0].Error(), "第0行"))
+	a.Equal(errs[1].Line, 1).True(errors.Is(errs[1], ErrUnknownKey))
+	a.Equal(errs[2].Line, 3).True(errors.Is(errs[2], ErrInvalidValue))
+	a.Equal(errs[3].Line, 5).True(errors.Is(errs[3], ErrInvalidValue))
+
+	s.AllowUnknownSections = true
+	s.Sections[0].Required = false
+	a.NotError(s.ValidateReader(NewReaderString("k=1\n[x]\ny=2\n[o]\nsize=16")))
+
+	// 无效的schema
+	s.Sections[1].Keys[0].Pattern = "["
+	err = s.ValidateReader(NewReaderString("[o]\nsize=1"))
+	a.Error(err).False(errors.As(err, &errs))
+
+	// 容错模式下的语法错误
+	s = loadTestSchema(a)
+	r := NewReaderString("name=1\n[server\nport=1\n[s]")
+	r.Tolerant()
+	err = s.ValidateReader(r)
+	errs = nil
+	a.True(errors.As(err, &errs)).Equal(len(errs), 4, err)
+	a.Equal(errs[0].Line, 0).True(errors.Is(errs[0], ErrRequired)) // [server]
+	a.Equal(errs[1].Line, 2).True(errors.Is(errs[1], ErrUnterminatedSection))
+	a.Equal(errs[2].Line, 3).True(errors.Is(errs[2], ErrUnknownKey))
+	a.Equal(errs[3].Line, 4).True(errors.Is(errs[3], ErrUnknownSection))
+}
+
+func TestSchema_Validate_concurrent(t *testing.T) {
+	a := assert.New(t)
+	s := loadTestSchema(a)
+
+	f, err := LoadFile(NewReaderString("name = app\n[server]\nport = 8080\nhost = example.com\n"))
+	a.NotError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.NotError(s.Validate(f))
+		}()
+	}
+	wg.Wait()
+}
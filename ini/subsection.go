@@ -0,0 +1,112 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"strings"
+)
+
+// Node表示section层级结构中的一个节点。
+type Node struct {
+	Name     string       // 节点名称，根节点为空
+	Path     []string     // 从根节点到当前节点的完整路径
+	Section  *FileSection // 对应的section，仅作为路径存在的中间节点为nil
+	Children []*Node      // 子节点，按在文件中出现的顺序排列
+}
+
+// 将section名称拆分成层级路径。支持以下两种格式，也可以混合使用：
+//  a.b.c            // ["a", "b", "c"]
+//  remote "origin"  // ["remote", "origin"]，引号中的内容作为一个整体
+func splitSection(name string) ([]string, error) {
+	var sub string
+	hasSub := false
+	if i := strings.IndexAny(name, "\"'"); i > -1 {
+		s, rest, err := unquote(name[i:])
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(rest)) > 0 {
+			return nil, errors.New("引号之后不能再有其它内容")
+		}
+
+		sub, hasSub = s, true
+		name = strings.TrimSpace(name[:i])
+	}
+
+	path := strings.Split(name, ".")
+	for i, p := range path {
+		if p = strings.TrimSpace(p); len(p) == 0 {
+			return nil, errors.New("section名称中包含空的层级")
+		}
+		path[i] = p
+	}
+
+	if hasSub {
+		path = append(path, sub)
+	}
+	return path, nil
+}
+
+// 将层级路径转换成section名称，是splitSection()的逆操作。
+// quoted表示最后一个元素是否需要以引号的形式输出。
+func joinSection(path []string, quoted bool) string {
+	if !quoted {
+		return strings.Join(path, ".")
+	}
+
+	last := path[len(path)-1]
+	return strings.Join(path[:len(path)-1], ".") + " " + quote(last)
+}
+
+// 将所有的section按名称组织成树状结构，根节点对应于全局的键值对。
+//
+// section名称的拆分规则与Reader.AllowSubsection()相同，
+// 存在多个相同路径的section时，Node.Section为最后一个。
+func (f *File) Tree() (*Node, error) {
+	root := &Node{Path: []string{}, Section: f.Global, Children: []*Node{}}
+
+	for _, s := range f.Sections {
+		path, err := splitSection(s.Name)
+		if err != nil {
+			return nil, errors.New("Tree:无效的section名称" + s.Name + "：" + err.Error())
+		}
+
+		n := root
+		for i, name := range path {
+			child := n.Child(name)
+			if child == nil {
+				child = &Node{Name: name, Path: path[:i+1], Children: []*Node{}}
+				n.Children = append(n.Children, child)
+			}
+			n = child
+		}
+		n.Section = s
+	}
+
+	return root, nil
+}
+
+// 查找名称为name的子节点，不存在则返回nil。
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// 根据路径查找节点，不存在则返回nil。
+func (n *Node) Find(path ...string) *Node {
+	for _, name := range path {
+		if n = n.Child(name); n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
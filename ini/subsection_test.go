@@ -0,0 +1,86 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestSplitSection(t *testing.T) {
+	a := assert.New(t)
+
+	path, err := splitSection("a.b.c")
+	a.NotError(err).Equal(path, []string{"a", "b", "c"})
+
+	path, err = splitSection(`remote "origin"`)
+	a.NotError(err).Equal(path, []string{"remote", "origin"})
+
+	path, err = splitSection(`a.b 'c.d'`)
+	a.NotError(err).Equal(path, []string{"a", "b", "c.d"})
+
+	path, err = splitSection("a..b")
+	a.Error(err).Nil(path)
+
+	path, err = splitSection(`"origin"`)
+	a.Error(err).Nil(path)
+}
+
+func TestJoinSection(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(joinSection([]string{"a", "b"}, false), "a.b")
+	a.Equal(joinSection([]string{"remote", `o"rigin`}, true), `remote "o\"rigin"`)
+
+	path, err := splitSection(joinSection([]string{"a", "b", "c.d e"}, true))
+	a.NotError(err).Equal(path, []string{"a", "b", "c.d e"})
+}
+
+func TestFile_Tree(t *testing.T) {
+	a := assert.New(t)
+
+	f, err := LoadFile(NewReaderString(`name=app
+[server.http]
+port=80
+[remote "origin"]
+url=git@example.com
+[server]
+host=localhost
+[remote "upstream"]
+[server.http]
+port=8080
+`))
+	a.NotError(err)
+
+	root, err := f.Tree()
+	a.NotError(err).NotNil(root)
+	a.Equal(root.Section, f.Global).Equal(len(root.Children), 2)
+
+	server := root.Child("server")
+	a.NotNil(server)
+	a.Equal(server.Path, []string{"server"}).
+		Equal(server.Section, f.Section("server"))
+
+	http := root.Find("server", "http")
+	a.NotNil(http)
+	a.Equal(http.Path, []string{"server", "http"}).
+		Equal(http.Section, f.Sections[4]) // 重复的section，取最后一个
+
+	remote := root.Child("remote")
+	a.NotNil(remote).Nil(remote.Section)
+	a.Equal(len(remote.Children), 2)
+	a.Equal(remote.Children[0].Name, "origin").
+		Equal(remote.Children[1].Name, "upstream")
+
+	a.Nil(root.Find("server", "not-exists"))
+	a.Equal(root.Find(), root)
+
+	// 无效的section名称
+	f, err = LoadFile(NewReaderString("[a..b]"))
+	a.NotError(err)
+	root, err = f.Tree()
+	a.Error(err).Nil(root)
+}
@@ -0,0 +1,313 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SystemdUnit表示合并了所有drop-in文件之后的systemd单元文件，用于读取配置项。
+//
+// 与普通的ini不同，同一section下可以有多个同名的键，表示一个列表，
+// 空的键值表示清空之前的列表，比如：
+//  [Service]
+//  ExecStart=
+//  ExecStart=/usr/bin/foo
+//
+// 若需要生成或是修改单元文件，可以使用SystemdDialect：
+//  w, err := ini.NewWriter(out, 0, ini.SystemdDialect)
+type SystemdUnit struct {
+	Name    string // 单元名称，比如foo@bar.service
+	entries []*systemdEntry
+}
+
+type systemdEntry struct {
+	section, key, value string
+}
+
+// 从fsys中加载名称为name的单元文件，以及与之对应的所有drop-in文件。
+//
+// drop-in文件为单元文件所在目录下以下目录中扩展名为.conf的文件，越靠后越优先：
+//  service.d/              // 同类型的所有单元
+//  foo-.service.d/         // 以foo-开头的所有单元，按`-`逐级匹配
+//  foo@.service.d/         // 实例化单元对应的模板
+//  foo@bar.service.d/      // 单元本身
+// 同名的drop-in文件只有最优先的一个有效，所有drop-in文件按文件名排序之后，
+// 依次在单元文件之后读取。若实例化单元对应的文件不存在，则读取其模板文件。
+func LoadSystemdUnit(fsys fs.FS, name string) (*SystemdUnit, error) {
+	dir, base := path.Split(name)
+	u := &SystemdUnit{Name: base}
+
+	prefix, instance, suffix := u.split()
+	if len(suffix) == 0 {
+		return nil, fmt.Errorf("LoadSystemdUnit:无效的单元名称%v", base)
+	}
+
+	err := u.load(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) && len(instance) > 0 {
+		err = u.load(fsys, path.Join(dir, prefix+"@"+suffix))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{suffix[1:] + ".d"}
+	for i, c := range prefix {
+		if c == '-' {
+			dirs = append(dirs, prefix[:i+1]+suffix+".d")
+		}
+	}
+	if len(instance) > 0 {
+		dirs = append(dirs, prefix+"@"+suffix+".d")
+	}
+	dirs = append(dirs, base+".d")
+
+	files := make(map[string]string)
+	for _, d := range dirs {
+		entries, err := fs.ReadDir(fsys, path.Join(dir, d))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && path.Ext(entry.Name()) == ".conf" {
+				files[entry.Name()] = path.Join(dir, d, entry.Name())
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		if err = u.load(fsys, files[n]); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// 加载文件p中的所有配置项。
+func (u *SystemdUnit) load(fsys fs.FS, p string) error {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := NewReader(f, SystemdDialect)
+	r.current().filename = p
+
+	var section string
+	for {
+		token, err := r.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token.Type {
+		case EOF:
+			return nil
+		case Section:
+			section = token.Value
+		case Element:
+			u.entries = append(u.entries, &systemdEntry{section: section, key: token.Key, value: token.Value})
+		}
+	}
+}
+
+// 获取所有的section名称，按第一次出现的顺序排列。
+func (u *SystemdUnit) Sections() []string {
+	var sections []string
+	found := make(map[string]bool)
+	for _, e := range u.entries {
+		if !found[e.section] {
+			found[e.section] = true
+			sections = append(sections, e.section)
+		}
+	}
+	return sections
+}
+
+// 获取section下名称为key的最后一个键值，键值可以为空。
+func (u *SystemdUnit) Get(section, key string) (string, bool) {
+	for i := len(u.entries) - 1; i >= 0; i-- {
+		if e := u.entries[i]; e.section == section && e.key == key {
+			return e.value, true
+		}
+	}
+
+	return "", false
+}
+
+// 以列表的形式获取section下名称为key的所有键值，空的键值会清空之前的所有内容。
+func (u *SystemdUnit) GetAll(section, key string) []string {
+	var vals []string
+	for _, e := range u.entries {
+		if e.section != section || e.key != key {
+			continue
+		}
+
+		if len(e.value) == 0 {
+			vals = vals[:0]
+		} else {
+			vals = append(vals, e.value)
+		}
+	}
+
+	return vals
+}
+
+// 将单元名称拆分成前缀、实例名称和类型后缀三部分，
+// 比如foo@bar.service拆分成foo、bar和.service。
+func (u *SystemdUnit) split() (prefix, instance, suffix string) {
+	i := strings.LastIndexByte(u.Name, '.')
+	if i <= 0 {
+		return u.Name, "", ""
+	}
+
+	prefix, suffix = u.Name[:i], u.Name[i:]
+	if j := strings.IndexByte(prefix, '@'); j > -1 {
+		prefix, instance = prefix[:j], prefix[j+1:]
+	}
+	return prefix, instance, suffix
+}
+
+// 展开s中的说明符，支持以下内容：
+//  %n  完整的单元名称
+//  %N  不包含类型后缀的单元名称
+//  %p  前缀，即@之前的内容
+//  %i  实例名称，即@与类型后缀之间的内容
+//  %I  转义还原之后的实例名称
+//  %j  前缀中最后一个`-`之后的内容
+//  %h  当前用户的主目录
+//  %u  当前用户的用户名
+//  %H  主机名
+//  %%  %本身
+// 其它的说明符将返回错误信息。
+func (u *SystemdUnit) Expand(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, nil
+	}
+
+	prefix, instance, suffix := u.split()
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			buf = append(buf, s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", errors.New("Expand:%之后缺少说明符")
+		}
+
+		var val string
+		switch s[i] {
+		case 'n':
+			val = u.Name
+		case 'N':
+			val = strings.TrimSuffix(u.Name, suffix)
+		case 'p':
+			val = prefix
+		case 'i':
+			val = instance
+		case 'I':
+			val = unescapeSystemd(instance)
+		case 'j':
+			val = prefix[strings.LastIndexByte(prefix, '-')+1:]
+		case 'h':
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			val = home
+		case 'u':
+			usr, err := user.Current()
+			if err != nil {
+				return "", err
+			}
+			val = usr.Username
+		case 'H':
+			host, err := os.Hostname()
+			if err != nil {
+				return "", err
+			}
+			val = host
+		case '%':
+			val = "%"
+		default:
+			return "", fmt.Errorf("Expand:无效的说明符%%%c", s[i])
+		}
+		buf = append(buf, val...)
+	}
+
+	return string(buf), nil
+}
+
+// 还原systemd的转义内容：`-`还原为`/`，\xNN还原为对应的字节。
+func unescapeSystemd(s string) string {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '-':
+			buf = append(buf, '/')
+		case s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x':
+			if b, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				buf = append(buf, byte(b))
+				i += 3
+				continue
+			}
+			buf = append(buf, s[i])
+		default:
+			buf = append(buf, s[i])
+		}
+	}
+
+	return string(buf)
+}
+
+// s是否以未被转义的`\`结尾
+func hasContinuation(s string) bool {
+	return (len(s)-len(strings.TrimRight(s, `\`)))%2 == 1
+}
+
+// 按systemd的规则解析键值，val为`=`之后的内容：
+// 键值原样返回，以`\`结尾的行，将`\`替换成空格之后与下一行合并，续行之间的注释行会被忽略。
+func (r *Reader) parseSystemdValue(val string) (string, error) {
+	for hasContinuation(val) {
+		next, ok, err := r.readLine()
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			val = val[:len(val)-1]
+			break
+		}
+
+		if len(r.dialect.commentPrefix(strings.TrimLeftFunc(next, unicode.IsSpace))) > 0 {
+			continue
+		}
+		val = val[:len(val)-1] + " " + next
+	}
+
+	return strings.TrimSpace(val), nil
+}
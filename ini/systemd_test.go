@@ -0,0 +1,159 @@
+// Copyright 2014 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package ini
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/issue9/assert"
+)
+
+func TestReader_parseSystemdValue(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewReaderString(`[Service]
+Environment="A=1" "B=2"
+ExecStart=/bin/echo a \
+    b \
+# comment
+  ; comment
+    c
+ExecStop = """x"""
+Escaped=a\\
+Empty=
+Last=x \`, SystemdDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: "Service"},
+		&Token{Type: Element, Key: "Environment", Value: `"A=1" "B=2"`},
+		&Token{Type: Element, Key: "ExecStart", Value: "/bin/echo a      b      c"},
+		&Token{Type: Element, Key: "ExecStop", Value: `"""x"""`},
+		&Token{Type: Element, Key: "Escaped", Value: `a\\`},
+		&Token{Type: Element, Key: "Empty", Value: ""},
+		&Token{Type: Element, Key: "Last", Value: "x"},
+	})
+}
+
+func TestWriter_Systemd(t *testing.T) {
+	a := assert.New(t)
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, 0, SystemdDialect)
+	a.NotError(err)
+	a.NotError(w.AddSection("Service"))
+	a.NotError(w.AddElement("Environment", `"A=1" "B=2"`))
+	a.NotError(w.AddElement("ExecStart", ""))
+	a.NotError(w.AddElement("ExecStart", "/usr/bin/foo --name %i"))
+	a.Error(w.AddElement("Key=", "v"))
+	a.Error(w.AddElement("#Key", "v"))
+	a.Error(w.AddElement(";Key", "v"))
+	a.Error(w.AddElement("[Key]", "v"))
+	a.Error(w.AddElement(`"Key"`, "v"))
+	a.Error(w.AddElement("'Key", "v"))
+	a.Error(w.AddElement("", "v"))
+	a.Error(w.AddElement("Multi", "a\nb"))
+	a.Error(w.AddElement("Space", " a"))
+	a.Error(w.AddElement("Cont", `a\`))
+	a.Error(w.AddElementComment("K", "v", "comment"))
+	w.Flush()
+	a.NotError(w.Err())
+	data := buf.String()
+	a.Equal(data, `[Service]
+Environment="A=1" "B=2"
+ExecStart=
+ExecStart=/usr/bin/foo --name %i
+`)
+
+	r := NewReaderString(data, SystemdDialect)
+	a.Equal(readTokens(a, r), []*Token{
+		&Token{Type: Section, Value: "Service"},
+		&Token{Type: Element, Key: "Environment", Value: `"A=1" "B=2"`},
+		&Token{Type: Element, Key: "ExecStart", Value: ""},
+		&Token{Type: Element, Key: "ExecStart", Value: "/usr/bin/foo --name %i"},
+	})
+}
+
+func TestLoadSystemdUnit(t *testing.T) {
+	a := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"units/foo-web@.service": &fstest.MapFile{Data: []byte(`[Unit]
+Description=web %i
+
+[Service]
+ExecStart=/usr/bin/web --port 80
+Environment=A=1
+Environment=B=2
+`)},
+		"units/service.d/00-all.conf":           &fstest.MapFile{Data: []byte("[Service]\nRestart=always\n")},
+		"units/foo-.service.d/10-env.conf":      &fstest.MapFile{Data: []byte("[Service]\nEnvironment=\nEnvironment=C=3\n")},
+		"units/foo-web@.service.d/20-exec.conf": &fstest.MapFile{Data: []byte("[Service]\nExecStart=\nExecStart=/usr/bin/web --port 8080\n")},
+		"units/foo-web@a.service.d/10-env.conf": &fstest.MapFile{Data: []byte("[Service]\nEnvironment=D=4\n")},
+		"units/foo-web@a.service.d/readme.txt":  &fstest.MapFile{Data: []byte("not ini")},
+		"units/foo-web@b.service.d/30-x.conf":   &fstest.MapFile{Data: []byte("[Install]\nWantedBy=multi-user.target\n")},
+	}
+
+	u, err := LoadSystemdUnit(fsys, "units/foo-web@a.service")
+	a.NotError(err).NotNil(u)
+	a.Equal(u.Name, "foo-web@a.service")
+	a.Equal(u.Sections(), []string{"Unit", "Service"})
+
+	val, found := u.Get("Service", "Restart")
+	a.True(found).Equal(val, "always")
+	val, found = u.Get("Service", "ExecStart")
+	a.True(found).Equal(val, "/usr/bin/web --port 8080")
+	_, found = u.Get("Install", "WantedBy")
+	a.False(found)
+
+	a.Equal(u.GetAll("Service", "ExecStart"), []string{"/usr/bin/web --port 8080"})
+	// foo-.service.d/10-env.conf被foo-web@a.service.d/10-env.conf覆盖
+	a.Equal(u.GetAll("Service", "Environment"), []string{"A=1", "B=2", "D=4"})
+
+	val, _ = u.Get("Unit", "Description")
+	val, err = u.Expand(val)
+	a.NotError(err).Equal(val, "web a")
+
+	u, err = LoadSystemdUnit(fsys, "units/foo-web@b.service")
+	a.NotError(err).NotNil(u)
+	a.Equal(u.GetAll("Service", "Environment"), []string{"C=3"})
+	val, _ = u.Get("Install", "WantedBy")
+	a.Equal(val, "multi-user.target")
+
+	u, err = LoadSystemdUnit(fsys, "units/bar.service")
+	a.Error(err).Nil(u)
+
+	u, err = LoadSystemdUnit(fsys, "units/foo")
+	a.Error(err).Nil(u)
+
+	// 语法错误
+	fsys["units/foo-web@a.service.d/99-bad.conf"] = &fstest.MapFile{Data: []byte("[Service\n")}
+	u, err = LoadSystemdUnit(fsys, "units/foo-web@a.service")
+	a.Error(err).Nil(u)
+	serr, ok := err.(*SyntaxError)
+	a.True(ok).Equal(serr.Filename, "units/foo-web@a.service.d/99-bad.conf")
+}
+
+func TestSystemdUnit_Expand(t *testing.T) {
+	a := assert.New(t)
+
+	u := &SystemdUnit{Name: "foo-bar@a-b\\x2dc.service"}
+	val, err := u.Expand("%n|%N|%p|%i|%I|%j|100%%")
+	a.NotError(err).Equal(val, "foo-bar@a-b\\x2dc.service|foo-bar@a-b\\x2dc|foo-bar|a-b\\x2dc|a/b-c|bar|100%")
+
+	home, err := os.UserHomeDir()
+	a.NotError(err)
+	val, err = u.Expand("%h/.config")
+	a.NotError(err).Equal(val, home+"/.config")
+
+	val, err = u.Expand("no specifier")
+	a.NotError(err).Equal(val, "no specifier")
+
+	_, err = u.Expand("%x")
+	a.Error(err)
+	_, err = u.Expand("abc%")
+	a.Error(err)
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"unicode"
 )
 
 // 用于输出ini内容到指定的io.Writer。
@@ -19,22 +20,40 @@ import (
 // 对于重复的键名和section名称并不会报错，若需要唯一值，
 // 需要用户自行解决。
 type Writer struct {
-	buf    *bufio.Writer
-	symbol byte
+	buf     *bufio.Writer
+	symbol  string
+	dialect *Dialect
+	err     error // 最后一次调用Flush()时的错误信息
 }
 
 // 声明一个新的Writer实例。
 //
 // w写入的io.Writer接口；
-// commentSymbol注释符号。只能是'#'或';'，传递其它参数将返回错误信息。
-func NewWriter(w io.Writer, commentSymbol byte) (*Writer, error) {
-	if commentSymbol != '#' && commentSymbol != ';' {
-		return nil, errors.New("NewWriter:注释符号只能是`;`或`#`")
+// commentSymbol注释符号。只能是Dialect.CommentPrefixes中的字符，
+// 默认情况下即'#'或';'，传递其它参数将返回错误信息；为0时使用Dialect.CommentPrefixes中的第一个。
+// d为可选的Dialect，用于指定分隔符、注释前缀等格式，默认为DefaultDialect。
+func NewWriter(w io.Writer, commentSymbol byte, d ...*Dialect) (*Writer, error) {
+	dialect := getDialect(d)
+
+	symbol := dialect.CommentPrefixes[0]
+	if commentSymbol != 0 {
+		symbol = string(commentSymbol)
+		found := false
+		for _, p := range dialect.CommentPrefixes {
+			if p == symbol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("NewWriter:注释符号只能是%v中的一个", strings.Join(dialect.CommentPrefixes, "、"))
+		}
 	}
 
 	return &Writer{
-		buf:    bufio.NewWriter(w),
-		symbol: commentSymbol,
+		buf:     bufio.NewWriter(w),
+		symbol:  symbol,
+		dialect: dialect,
 	}, nil
 }
 
@@ -44,16 +63,20 @@ func (w *Writer) NewLine() error {
 }
 
 // 添加section，section没有嵌套功能，添加一个新的Section，意味着前一个section的结束。
-// section名称只能在同一行，若section值中包含换行符，则会返回错误信息。
+// section名称只能在同一行，若section值中包含换行符或是`]`，则会返回错误信息。
 func (w *Writer) AddSection(section string) (err error) {
 	if len(section) == 0 {
 		return errors.New("AddSection:section名称不能为空值")
 	}
 
-	if strings.IndexByte(section, '\n') > -1 {
+	if strings.ContainsAny(section, "\r\n") {
 		return errors.New("AddSection:section名称中不能包含换行符")
 	}
 
+	if strings.IndexByte(section, ']') > -1 {
+		return errors.New("AddSection:section名称中不能包含`]`")
+	}
+
 	if err = w.buf.WriteByte('['); err != nil {
 		return err
 	}
@@ -67,28 +90,185 @@ func (w *Writer) AddSection(section string) (err error) {
 }
 
 // 添加一个键值对。
-func (w *Writer) AddElement(key, val string) (err error) {
+//
+// 若val中包含换行符，则会以`"""`包含的多行形式输出，
+// 启用了Dialect.IndentContinuation时，则优先以缩进的形式输出；
+// 若key或val的内容无法被Reader原样读取，比如包含首尾空格、以引号开头等，
+// 则会以双引号包含并转义之后输出，保证输出的内容总是可以被Reader正确读取。
+// 使用GitDialect时，则按git config的规则转义，且键名只能包含字母、数字和`-`；
+// 使用SystemdDialect和DesktopDialect时，键值原样输出，无法被原样读取的内容将返回错误信息，
+// 比如以注释符号、`[`或是引号开头的键名。
+func (w *Writer) AddElement(key, val string) error {
+	if err := w.writeElement(key, val); err != nil {
+		return err
+	}
+
+	return w.NewLine()
+}
+
+// 添加一个带行尾注释的键值对。
+//
+// 读取时，需要调用Reader.AllowInlineComment()才能正确识别行尾注释。
+// 多行内容无法添加行尾注释，注释内容也不能包含换行符。
+func (w *Writer) AddElementComment(key, val, comment string) (err error) {
+	if strings.IndexAny(comment, "\r\n") > -1 {
+		return errors.New("AddElementComment:注释中不能包含换行符")
+	}
+
+	if w.dialect.style == styleSystemd || w.dialect.style == styleRaw {
+		return errors.New("AddElementComment:当前格式不支持行尾注释")
+	}
+
+	if w.dialect.style != styleGit && strings.IndexByte(val, '\n') > -1 && (canBlock(val) || w.canIndent(val)) {
+		return errors.New("AddElementComment:多行内容不能添加行尾注释")
+	}
+
+	if err = w.writeElement(key, val); err != nil {
+		return err
+	}
+
+	if err = w.buf.WriteByte(' '); err != nil {
+		return err
+	}
+
+	if _, err = w.buf.WriteString(w.symbol); err != nil {
+		return err
+	}
+
+	if _, err = w.buf.WriteString(comment); err != nil {
+		return err
+	}
+
+	return w.NewLine()
+}
+
+// 输出键值对，不包含最后的换行符。
+func (w *Writer) writeElement(key, val string) (err error) {
 	if len(key) == 0 { // val可以为空，key不能为空
 		return errors.New("AddElement:参数key不能为空")
 	}
 
-	if strings.IndexByte(key, '\n') > -1 || strings.IndexByte(val, '\n') > -1 {
-		return errors.New("AddElement:参数key和val都不能包含换行符")
+	switch w.dialect.style {
+	case styleGit:
+		return w.writeGitElement(key, val)
+	case styleSystemd, styleRaw:
+		return w.writeRawElement(key, val)
+	}
+
+	if keyNeedQuote(key, w.dialect) {
+		key = quote(key)
+	}
+
+	switch {
+	case w.canIndent(val):
+		val = strings.Replace(val, "\n", "\n    ", -1)
+	case canBlock(val):
+		val = blockDelim + "\n" + val + "\n" + blockDelim
+	case valueNeedQuote(val, w.dialect):
+		val = quote(val)
 	}
 
 	if _, err = w.buf.WriteString(key); err != nil {
 		return err
 	}
 
-	if err = w.buf.WriteByte('='); err != nil {
+	delim := w.dialect.Delimiters[0]
+	if w.dialect.SpaceAroundDelimiter {
+		delim = " " + delim + " "
+	}
+	if _, err = w.buf.WriteString(delim); err != nil {
 		return err
 	}
 
-	if _, err = w.buf.WriteString(val); err != nil {
+	_, err = w.buf.WriteString(val)
+	return err
+}
+
+// 按git config的格式输出键值对，键名以tab缩进，键值按git的规则转义。
+func (w *Writer) writeGitElement(key, val string) (err error) {
+	if !isGitKey(key) {
+		return fmt.Errorf("AddElement:无效的键名%v，只能包含字母、数字和`-`，且以字母开头", key)
+	}
+
+	if err = w.buf.WriteByte('\t'); err != nil {
 		return err
 	}
 
-	return w.NewLine()
+	if _, err = w.buf.WriteString(key); err != nil {
+		return err
+	}
+
+	if _, err = w.buf.WriteString(" = "); err != nil {
+		return err
+	}
+
+	_, err = w.buf.WriteString(gitQuote(val))
+	return err
+}
+
+// 原样输出键值对，用于systemd和.desktop等不处理转义字符的格式，
+// 无法被原样读取的内容将返回错误信息。
+func (w *Writer) writeRawElement(key, val string) (err error) {
+	// 以注释符号、[或是引号开头的键名，读取时会被当作注释、section或是带引号的键名。
+	if len(key) == 0 || key != strings.TrimSpace(key) || strings.ContainsAny(key, "=\r\n") ||
+		strings.IndexByte("[\"'", key[0]) > -1 || len(w.dialect.commentPrefix(key)) > 0 {
+		return fmt.Errorf("AddElement:无效的键名%v", key)
+	}
+
+	switch {
+	case strings.ContainsAny(val, "\r\n"):
+		return errors.New("AddElement:键值中不能包含换行符")
+	case val != strings.TrimSpace(val):
+		return errors.New("AddElement:键值的首尾不能包含空白字符")
+	case w.dialect.style == styleSystemd && hasContinuation(val):
+		return errors.New("AddElement:键值不能以\\结尾")
+	}
+
+	if _, err = w.buf.WriteString(key); err != nil {
+		return err
+	}
+
+	if err = w.buf.WriteByte('='); err != nil {
+		return err
+	}
+
+	_, err = w.buf.WriteString(val)
+	return err
+}
+
+// 键值val是否适合以多行的形式输出。
+func canBlock(val string) bool {
+	if strings.IndexByte(val, '\n') < 0 || strings.IndexByte(val, '\r') > -1 {
+		return false
+	}
+
+	for _, line := range strings.Split(val, "\n") {
+		if strings.HasSuffix(strings.TrimRightFunc(line, unicode.IsSpace), blockDelim) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 在启用了Dialect.IndentContinuation时，键值val是否适合以缩进的形式输出多行内容。
+func (w *Writer) canIndent(val string) bool {
+	if !w.dialect.IndentContinuation || strings.IndexByte(val, '\n') < 0 {
+		return false
+	}
+
+	lines := strings.Split(val, "\n")
+	if valueNeedQuote(lines[0], w.dialect) {
+		return false
+	}
+
+	for _, line := range lines[1:] {
+		if len(line) == 0 || line != strings.TrimSpace(line) || len(w.dialect.commentPrefix(line)) > 0 {
+			return false
+		}
+	}
+
+	return true
 }
 
 // 添加一个键值对。val使用fmt.Sprint格式化成字符串。
@@ -103,10 +283,10 @@ func (w *Writer) AddElementf(key string, val interface{}) error {
 // 所以若传递一个仅有\n的字符串，最终将输出2行空注释。
 func (w *Writer) AddComment(comment string) (err error) {
 	if strings.IndexByte(comment, '\n') > -1 { // 存在换行符
-		comment = strings.Replace(comment, "\n", "\n"+string(w.symbol), -1)
+		comment = strings.Replace(comment, "\n", "\n"+w.symbol, -1)
 	}
 
-	if err = w.buf.WriteByte(w.symbol); err != nil {
+	if _, err = w.buf.WriteString(w.symbol); err != nil {
 		return err
 	}
 
@@ -117,7 +297,18 @@ func (w *Writer) AddComment(comment string) (err error) {
 	return w.NewLine()
 }
 
-// 将内容输出到io.Writer中
+// 原样输出一行内容，line应该包含其自身的换行符。
+func (w *Writer) writeRaw(line string) (err error) {
+	_, err = w.buf.WriteString(line)
+	return err
+}
+
+// 将内容输出到io.Writer中，出错时可以通过Err()获取错误信息。
 func (w *Writer) Flush() {
-	w.buf.Flush()
+	w.err = w.buf.Flush()
+}
+
+// 返回最后一次调用Flush()时的错误信息
+func (w *Writer) Err() error {
+	return w.err
 }
@@ -117,3 +117,114 @@ func TestWrite(t *testing.T) {
 		a.Equal(buf.String(), test.value)
 	}
 }
+
+func TestWriter_Err(t *testing.T) {
+	a := assert.New(t)
+
+	w, err := NewWriter(&errWriter{}, 0)
+	a.NotError(err).NotNil(w)
+	a.NotError(w.Err())
+
+	a.NotError(w.AddElement("k", "v"))
+	w.Flush()
+	a.Error(w.Err())
+}
+
+func TestWriter_AddSection(t *testing.T) {
+	a := assert.New(t)
+	buf := new(bytes.Buffer)
+
+	w, err := NewWriter(buf, 0)
+	a.NotError(err)
+	a.NotError(w.AddSection(`remote "origin"`))
+	a.Error(w.AddSection(""))
+	a.Error(w.AddSection("a]b"))
+	a.Error(w.AddSection("a\nb"))
+	a.Error(w.AddSection("a\rb"))
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), "[remote \"origin\"]\n")
+}
+
+func TestWriter_AddElement(t *testing.T) {
+	a := assert.New(t)
+	buf := new(bytes.Buffer)
+
+	vals := []string{
+		"line 1\nline 2",
+		"  line 1\n\nline 2  \n",
+		"\n",
+		`C:\`,
+		`"""value`,
+		"line 1\nline 2\"\"\"",
+		"line 1\r\nline 2",
+		"  value  ",
+		"'value'",
+		"",
+	}
+	keys := []string{"key", "key", "key", "key", "key", "key", "key", " key ", "k=ey", `"key`}
+	w, err := NewWriter(buf, '#')
+	a.NotError(err)
+	for i, val := range vals {
+		a.NotError(w.AddElement(keys[i], val))
+	}
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), `key="""
+line 1
+line 2
+"""
+key="""
+  line 1
+
+line 2  
+
+"""
+key="""
+
+
+"""
+key="C:\\"
+key="\"\"\"value"
+key="line 1\nline 2\"\"\""
+key="line 1\r\nline 2"
+" key "="  value  "
+"k=ey"="'value'"
+"\"key"=
+`)
+
+	// 可以被Reader正确读取
+	r := NewReaderBytes(buf.Bytes())
+	for i, val := range vals {
+		token, err := r.Token()
+		a.NotError(err).
+			Equal(token.Key, keys[i]).
+			Equal(token.Value, val)
+	}
+
+	a.Error(w.AddElement("", "val"))
+}
+
+func TestWriter_AddElementComment(t *testing.T) {
+	a := assert.New(t)
+	buf := new(bytes.Buffer)
+
+	w, err := NewWriter(buf, ';')
+	a.NotError(err)
+	a.NotError(w.AddElementComment("port", "8080", " default"))
+	a.NotError(w.AddElementComment("path", "a ;b", "comment"))
+	a.Error(w.AddElementComment("key", "val", "line1\nline2"))
+	a.Error(w.AddElementComment("key", "line1\nline2", "comment"))
+	w.Flush()
+	a.NotError(w.Err())
+	a.Equal(buf.String(), `port=8080 ; default
+path="a ;b" ;comment
+`)
+
+	r := NewReaderBytes(buf.Bytes())
+	r.AllowInlineComment()
+	token, err := r.Token()
+	a.NotError(err).Equal(token.Value, "8080").Equal(token.Comment, " default")
+	token, err = r.Token()
+	a.NotError(err).Equal(token.Value, "a ;b").Equal(token.Comment, "comment")
+}
@@ -95,6 +95,17 @@ func (v *SemVersion) String() string {
 	return buf.String()
 }
 
+// 实现 encoding.TextMarshaler 接口
+func (v *SemVersion) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// 实现 encoding.TextUnmarshaler 接口
+func (v *SemVersion) UnmarshalText(data []byte) error {
+	*v = SemVersion{}
+	return Parse(v, string(data))
+}
+
 func SemVer(ver string) (*SemVersion, error) {
 	semver := &SemVersion{}
 
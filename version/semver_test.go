@@ -77,6 +77,17 @@ func TestSemVersion_String(t *testing.T) {
 	a.Equal(sv.String(), "1.22.1234-alpha1.0")
 }
 
+func TestSemVersion_Text(t *testing.T) {
+	a := assert.New(t)
+
+	sv := &SemVersion{Major: 1, Minor: 2, PreRelease: "alpha"}
+	data, err := sv.MarshalText()
+	a.NotError(err).Equal(string(data), "1.2.0-alpha")
+
+	a.NotError(sv.UnmarshalText([]byte("2.0.1+build")))
+	a.Equal(sv, &SemVersion{Major: 2, Patch: 1, Build: "build"})
+}
+
 func TestSemVerCompare(t *testing.T) {
 	a := assert.New(t)
 
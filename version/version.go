@@ -130,7 +130,7 @@ func getFields(obj interface{}) (map[int]*field, error) {
 		case "string":
 			field.Type = fieldTypeString
 		default:
-			return nil, fmt.Errorf("字段[%v]包含无效的标签：", name, tags[1])
+			return nil, fmt.Errorf("字段[%v]包含无效的标签：%v", name, tags[1])
 		}
 
 		// tags[2...]